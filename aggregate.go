@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Feeds one status page from several backends at once, e.g. our own Slack
+// and a neighbouring network's.
+type CSPAggregate struct {
+	services []CSPService
+
+	page *CSPPage
+}
+
+func NewCSPAggregate(services []CSPService) (app CSPAggregate) {
+	app.page = &CSPPage{}
+	app.services = services
+	app.page.showOrigin = true
+	for _, service := range services {
+		app.page.componentOrder = append(app.page.componentOrder, service.Page().componentOrder...)
+	}
+	app.merge()
+	return app
+}
+
+func (app *CSPAggregate) BuildStatusPage() (err error) {
+	for _, service := range app.services {
+		err = service.BuildStatusPage()
+		if err != nil {
+			return err
+		}
+	}
+	app.merge()
+	return nil
+}
+
+// Every backend rebuilds its own page whenever it feels like it, so merge
+// them right before we serve anything.
+func (app *CSPAggregate) StatusPage(gin *gin.Context) {
+	app.merge()
+	app.page.statusPage(gin)
+}
+
+func (app *CSPAggregate) Page() *CSPPage {
+	app.merge()
+	return app.page
+}
+
+func (app *CSPAggregate) SendReminders(now bool) error {
+	for _, service := range app.services {
+		err := service.SendReminders(now)
+		if err != nil {
+			// Don't let one broken backend keep the others quiet
+			log.Printf("Could not send reminders: %s\n", err)
+		}
+	}
+	return nil
+}
+
+// Only fails if nobody heard it
+func (app *CSPAggregate) Announce(message string) error {
+	var lastErr error
+	announced := false
+	for _, service := range app.services {
+		err := service.Announce(message)
+		if err != nil {
+			log.Printf("Could not announce: %s\n", err)
+			lastErr = err
+			continue
+		}
+		announced = true
+	}
+	if !announced && lastErr != nil {
+		return fmt.Errorf("no source could announce: %w", lastErr)
+	}
+	return nil
+}
+
+// Only the backend the update came from can unpin it
+func (app *CSPAggregate) Unpin(updateID string, note string) error {
+	for _, service := range app.services {
+		pinnedUpdates, _ := service.Page().snapshot()
+		for _, update := range pinnedUpdates {
+			if update.ID == updateID {
+				return service.Unpin(updateID, note)
+			}
+		}
+	}
+	return fmt.Errorf("no source has %s pinned", updateID)
+}
+
+func (app *CSPAggregate) Run() {
+	for _, service := range app.services {
+		go service.Run()
+	}
+	select {}
+}
+
+func (app *CSPAggregate) merge() {
+	pages := make([]*CSPPage, 0, len(app.services))
+	for _, service := range app.services {
+		pages = append(pages, service.Page())
+	}
+	app.page.setUpdates(mergePages(pages))
+	app.page.setPreviews(mergePreviews(pages))
+}
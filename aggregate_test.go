@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAggregateAnnounce(t *testing.T) {
+	down := &fakeService{page: &CSPPage{}, announceErr: errors.New("down")}
+	up := &fakeService{page: &CSPPage{}}
+
+	app := NewCSPAggregate([]CSPService{down, up})
+	if err := app.Announce("Heads up"); err != nil {
+		t.Errorf("Expected one backend hearing it to be enough, got %s", err)
+	}
+
+	app = NewCSPAggregate([]CSPService{down, down})
+	if err := app.Announce("Heads up"); err == nil {
+		t.Error("Expected an error when nobody could announce")
+	}
+}
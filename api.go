@@ -0,0 +1,212 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The same things that are on the page, for anything that would rather not
+// scrape HTML. Kept separate from the page's structs so we can change the page
+// without breaking anyone.
+type apiStatus struct {
+	Org         string         `json:"org"`
+	Overall     apiOverall     `json:"overall"`
+	Components  []apiComponent `json:"components"`
+	Maintenance []Maintenance  `json:"maintenance"`
+	Pinned      []apiUpdate    `json:"pinned"`
+	Updates     []apiUpdate    `json:"updates"`
+}
+
+type apiOverall struct {
+	Severity string `json:"severity,omitempty"`
+	Impact   string `json:"impact"`
+	Headline string `json:"headline"`
+}
+
+type apiComponent struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Group       string   `json:"group,omitempty"`
+	Status      string   `json:"status"`
+	Impact      string   `json:"impact"`
+	Uptime      *float64 `json:"uptime"`
+	Days        []apiDay `json:"days"`
+}
+
+type apiDay struct {
+	Date            string   `json:"date"`
+	Status          string   `json:"status,omitempty"`
+	Uptime          *float64 `json:"uptime"`
+	DegradedMinutes int      `json:"degraded_minutes"`
+	OutageMinutes   int      `json:"outage_minutes"`
+}
+
+type apiReply struct {
+	HTML   string    `json:"html"`
+	SentBy string    `json:"sent_by"`
+	State  string    `json:"state,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+type apiUpdate struct {
+	ID                 string        `json:"id"`
+	HTML               string        `json:"html"`
+	SentBy             string        `json:"sent_by"`
+	Origin             string        `json:"origin,omitempty"`
+	Components         []string      `json:"components,omitempty"`
+	Severity           string        `json:"severity,omitempty"`
+	Impact             string        `json:"impact,omitempty"`
+	State              string        `json:"state,omitempty"`
+	States             []StateChange `json:"states,omitempty"`
+	Replies            []apiReply    `json:"replies,omitempty"`
+	ExpectedResolution *time.Time    `json:"expected_resolution,omitempty"`
+	Time               time.Time     `json:"time"`
+}
+
+func (page *CSPPage) statusAPI(c *gin.Context) {
+	pinnedUpdates, updates := page.current()
+	overall := overallStatus(pinnedUpdates)
+	status := apiStatus{
+		Org:         config().OrgName,
+		Overall:     apiOverall{Severity: overall.Severity, Impact: overall.Impact, Headline: overall.Headline},
+		Components:  []apiComponent{},
+		Maintenance: []Maintenance{},
+		Pinned:      apiUpdates(pinnedUpdates),
+		Updates:     apiUpdates(updates),
+	}
+	for _, maintenance := range store.maintenances() {
+		if maintenance.Status == MaintenanceScheduled || maintenance.Status == MaintenanceInProgress {
+			status.Maintenance = append(status.Maintenance, maintenance)
+		}
+	}
+	for _, group := range componentStatuses(pinnedUpdates) {
+		for _, component := range group.Components {
+			status.Components = append(status.Components, newAPIComponent(component))
+		}
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func newAPIComponent(status ComponentStatus) (component apiComponent) {
+	component.Name = status.Name
+	component.Description = status.Description
+	component.Group = status.Group
+	component.Status = status.Severity
+	if component.Status == "" {
+		component.Status = resolvedSeverity()
+	}
+	component.Impact = severityImpact(component.Status)
+	if status.Uptime.HasData {
+		percent := status.Uptime.Percent
+		component.Uptime = &percent
+	}
+	for _, day := range status.Uptime.Days {
+		d := apiDay{
+			Date:            day.Date.Format("2006-01-02"),
+			Status:          day.Severity,
+			DegradedMinutes: int(day.Degraded / time.Minute),
+			OutageMinutes:   int(day.Outage / time.Minute),
+		}
+		if day.Tracked > 0 {
+			percent := 100 * float64(day.Tracked-day.Outage) / float64(day.Tracked)
+			d.Uptime = &percent
+		}
+		component.Days = append(component.Days, d)
+	}
+	return component
+}
+
+func apiUpdates(updates []StatusUpdate) []apiUpdate {
+	converted := make([]apiUpdate, 0, len(updates))
+	for _, update := range updates {
+		var resolution *time.Time
+		if !update.ExpectedResolution.IsZero() {
+			expected := update.ExpectedResolution
+			resolution = &expected
+		}
+		converted = append(converted, apiUpdate{
+			ExpectedResolution: resolution,
+			ID:                 update.ID,
+			HTML:               string(update.HTML),
+			SentBy:             update.SentBy,
+			Origin:             update.Origin,
+			Components:         update.Components,
+			Severity:           update.Severity,
+			Impact:             severityImpact(update.Severity),
+			State:              update.State,
+			States:             update.StateHistory,
+			Replies:            apiReplies(update.Replies),
+			Time:               update.Time,
+		})
+	}
+	return converted
+}
+
+func apiReplies(replies []StatusReply) (converted []apiReply) {
+	for _, reply := range replies {
+		converted = append(converted, apiReply{
+			HTML:   string(reply.HTML),
+			SentBy: reply.SentBy,
+			State:  reply.State,
+			Time:   reply.Time,
+		})
+	}
+	return converted
+}
+
+// Anything that changes the page needs CSP_API_TOKEN. Without one, the API
+// stays read-only.
+func apiAuthorized(c *gin.Context) bool {
+	conf := config()
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if conf.APIToken == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(conf.APIToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return false
+	}
+	return true
+}
+
+func createMaintenanceAPI(c *gin.Context) {
+	if !apiAuthorized(c) {
+		return
+	}
+	var maintenance Maintenance
+	err := c.ShouldBindJSON(&maintenance)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	maintenance.ID = ""
+	if maintenance.CreatedBy == "" {
+		maintenance.CreatedBy = "API"
+	}
+	err = maintenance.validate(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err = store.setMaintenance(maintenance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	advanceMaintenance(time.Now())
+	maintenance, _ = store.maintenance(maintenance.ID)
+	c.JSON(http.StatusCreated, maintenance)
+}
+
+func cancelMaintenanceAPI(c *gin.Context) {
+	if !apiAuthorized(c) {
+		return
+	}
+	maintenance, err := cancelMaintenance(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, maintenance)
+}
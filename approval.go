@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// When a second person has to sign off on an update before it goes live
+const (
+	ApprovalOff      = "off"
+	ApprovalAll      = "all"
+	ApprovalCritical = "critical"
+)
+
+type Approval struct {
+	RequestedBy string    `json:"requested_by"`
+	RequestedAt time.Time `json:"requested_at"`
+	ApprovedBy  string    `json:"approved_by,omitempty"`
+	ApprovedAt  time.Time `json:"approved_at"`
+
+	// The message with the approve button on it, so we can update it once
+	// it's been pressed
+	MessageID string `json:"message_id,omitempty"`
+}
+
+func (approval Approval) pending() bool {
+	return approval.ApprovedBy == ""
+}
+
+func parseApprovalMode(value string) string {
+	switch value {
+	case "", ApprovalOff:
+		return ApprovalOff
+	case ApprovalAll, ApprovalCritical:
+		return value
+	}
+	log.Printf("Unknown approval mode '%s', turning approvals off.\n", value)
+	return ApprovalOff
+}
+
+// Whether an update with the given severity needs a second person to approve
+// it. New updates don't have a severity yet.
+func approvalRequired(severity string) bool {
+	switch config().ApprovalMode {
+	case ApprovalAll:
+		return true
+	case ApprovalCritical:
+		return severityImpact(severity) == SeverityError
+	}
+	return false
+}
+
+// Whether an update has to stay off the page until someone approves it. One
+// that needs approving but never got asked about, because we weren't
+// connected when it was posted or asking failed, waits too.
+func awaitingApproval(updateID string, severity string, posted time.Time) bool {
+	mode := config().ApprovalMode
+	since := store.approvalsSince(mode == ApprovalAll || mode == ApprovalCritical, time.Now())
+	if approval, ok := store.approval(updateID); ok {
+		return approval.pending()
+	}
+	return approvalRequired(severity) && !posted.Before(since)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApprovalRequired(t *testing.T) {
+	cases := []struct {
+		mode     string
+		severity string
+		expected bool
+	}{
+		{parseApprovalMode(""), SeverityError, false},
+		{parseApprovalMode("all"), "", true},
+		{parseApprovalMode("all"), SeverityOK, true},
+		{parseApprovalMode("critical"), "", false},
+		{parseApprovalMode("critical"), SeverityWarn, false},
+		{parseApprovalMode("critical"), SeverityError, true},
+		{parseApprovalMode("sometimes"), SeverityError, false},
+	}
+	for _, c := range cases {
+		withConfig(t, func(next *Config) { next.ApprovalMode = c.mode })
+		if approvalRequired(c.severity) != c.expected {
+			t.Errorf("Approval for '%s' in mode '%s' did not match.\nExpected: %t", c.severity, c.mode, c.expected)
+		}
+	}
+}
+
+func TestAwaitingApproval(t *testing.T) {
+	defer func(s *CSPStore) { store = s }(store)
+	store = &CSPStore{}
+	withConfig(t, func(c *Config) { c.ApprovalMode = ApprovalCritical })
+	now := time.Now()
+	since := store.approvalsSince(true, now)
+
+	if awaitingApproval("C123/1.0", SeverityError, now.Add(-time.Hour)) {
+		t.Error("Expected updates from before approvals were turned on to be left alone")
+	}
+	// Posted while we were disconnected, so nobody ever asked
+	if !awaitingApproval("C123/2.0", SeverityError, now.Add(time.Minute)) {
+		t.Error("Expected a critical update nobody asked about to be held")
+	}
+	if awaitingApproval("C123/3.0", SeverityWarn, now.Add(time.Minute)) {
+		t.Error("Expected updates that don't need approving to go up")
+	}
+
+	store.setApproval("C123/4.0", Approval{RequestedBy: "U1", ApprovedBy: "U2"})
+	if awaitingApproval("C123/4.0", SeverityError, now.Add(time.Minute)) {
+		t.Error("Expected an approved update to go up")
+	}
+
+	// Turning approvals off and on again starts over
+	withConfig(t, func(c *Config) { c.ApprovalMode = ApprovalOff })
+	awaitingApproval("C123/5.0", SeverityError, now)
+	withConfig(t, func(c *Config) { c.ApprovalMode = ApprovalAll })
+	if awaitingApproval("C123/5.0", SeverityError, now) || !store.approvalsSince(true, now).After(since) {
+		t.Error("Expected approvals to start counting again once they're back on")
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Maintenance and incidents as an iCalendar feed (RFC 5545), so people can
+// plan around us from their calendar app. Simple enough to write out by hand.
+
+const icsTimeFormat = "20060102T150405Z"
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+type calendarEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Cancelled   bool
+}
+
+func (page *CSPPage) calendar(c *gin.Context) {
+	// Maintenance gets its own events, so leave it out of the updates
+	pinnedUpdates, updates := page.snapshot()
+	events := maintenanceEvents(store.maintenances())
+	events = append(events, incidentEvents(pinnedUpdates, time.Now())...)
+	events = append(events, incidentEvents(updates, time.Time{})...)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(buildCalendar(events, time.Now())))
+}
+
+func maintenanceEvents(maintenances []Maintenance) (events []calendarEvent) {
+	for _, maintenance := range maintenances {
+		description := maintenance.Description
+		if len(maintenance.Components) > 0 {
+			description = strings.TrimSpace(fmt.Sprintf("Affects %s.\n\n%s", strings.Join(maintenance.Components, ", "), description))
+		}
+		events = append(events, calendarEvent{
+			UID:         "maintenance-" + maintenance.ID,
+			Summary:     "Maintenance: " + maintenance.Title,
+			Description: description,
+			Start:       maintenance.Start,
+			End:         maintenance.End,
+			Cancelled:   maintenance.Status == MaintenanceCancelled,
+		})
+	}
+	return events
+}
+
+// Anything that was ever more than informational counts as an incident. It
+// ended when it was resolved or came off the page, whichever happened first.
+// Ones that are still going end now.
+func incidentEvents(updates []StatusUpdate, ongoing time.Time) (events []calendarEvent) {
+	for _, update := range updates {
+		if impact := severityImpact(update.Severity); impact != SeverityWarn && impact != SeverityError && len(update.StateHistory) == 0 {
+			continue
+		}
+		text := updateText(update.HTML)
+		event := calendarEvent{
+			UID:         "incident-" + update.ID,
+			Summary:     "Incident: " + firstLine(text),
+			Description: text,
+			Start:       update.Time,
+			End:         ongoing,
+		}
+		if update.ID == "" {
+			hash := fnv.New64a()
+			hash.Write([]byte(text))
+			event.UID = fmt.Sprintf("incident-%d-%x", update.Time.Unix(), hash.Sum64())
+		}
+		for _, change := range update.StateHistory {
+			if change.State == StateResolved {
+				event.End = change.Time
+				break
+			}
+		}
+		// Anything still pinned has been re-pinned since, if it was ever unpinned
+		if unpinned, ok := store.unpinnedAt(update.ID); ok && ongoing.IsZero() && update.ID != "" && (event.End.IsZero() || unpinned.Before(event.End)) {
+			event.End = unpinned
+		}
+		if !event.End.After(event.Start) {
+			event.End = time.Time{}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// The update as plain text, for places that can't show HTML
+func updateText(rendered template.HTML) string {
+	text := htmlTagRegex.ReplaceAllString(string(rendered), "")
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return truncateText(strings.TrimSpace(line), 80)
+}
+
+func buildCalendar(events []calendarEvent, now time.Time) string {
+	c := config()
+	host := "cursed-status-page"
+	if u, err := url.Parse(c.PublicURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	name := "Status"
+	if c.OrgName != "" {
+		name = c.OrgName + " Status"
+	}
+
+	var b strings.Builder
+	write := func(line string) {
+		b.WriteString(foldICSLine(line))
+	}
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//cursed-status-page//EN")
+	write("CALSCALE:GREGORIAN")
+	write("METHOD:PUBLISH")
+	write("X-WR-CALNAME:" + escapeICSText(name))
+	for _, event := range events {
+		write("BEGIN:VEVENT")
+		write("UID:" + escapeICSText(event.UID+"@"+host))
+		write("DTSTAMP:" + now.UTC().Format(icsTimeFormat))
+		write("DTSTART:" + event.Start.UTC().Format(icsTimeFormat))
+		if !event.End.IsZero() {
+			write("DTEND:" + event.End.UTC().Format(icsTimeFormat))
+		}
+		write("SUMMARY:" + escapeICSText(event.Summary))
+		if event.Description != "" {
+			write("DESCRIPTION:" + escapeICSText(event.Description))
+		}
+		if event.Cancelled {
+			write("STATUS:CANCELLED")
+		} else {
+			write("STATUS:CONFIRMED")
+		}
+		if c.PublicURL != "" {
+			write("URL:" + c.PublicURL)
+		}
+		write("END:VEVENT")
+	}
+	write("END:VCALENDAR")
+	return b.String()
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICSText(text string) string {
+	return icsEscaper.Replace(text)
+}
+
+// Lines can't be longer than 75 bytes. Longer ones carry on onto the next
+// line after a space, taking care not to split a character in half.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFoldICSLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("é", 60)
+	folded := foldICSLine(line)
+	for _, part := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("Line is %d bytes long: %s", len(part), part)
+		}
+	}
+	if unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", ""); unfolded != line {
+		t.Errorf("Unfolding did not give back the original line.\nExpected: %s\nReceived: %s", line, unfolded)
+	}
+}
+
+func TestBuildCalendar(t *testing.T) {
+	defer func(s *CSPStore) { store = s }(store)
+	store = &CSPStore{}
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	incident := StatusUpdate{ID: "C123/1709294400.000100", HTML: "<p>Backbone <strong>down</strong>; investigating, sorry</p>", Time: start}
+	incident.setSeverity(SeverityError)
+	incident.setIncidentStates([]StateChange{{Time: start, State: StateInvestigating}, {Time: start.Add(90 * time.Minute), State: StateResolved}})
+	info := StatusUpdate{ID: "C123/1709294400.000200", HTML: "<p>New volunteers welcome</p>", Time: start}
+	info.setSeverity(SeverityOK)
+
+	events := incidentEvents([]StatusUpdate{incident, info}, time.Time{})
+	events = append(events, maintenanceEvents([]Maintenance{{ID: "abc", Title: "Router swap", Start: start, End: start.Add(time.Hour), Status: MaintenanceCancelled}})...)
+	calendar := buildCalendar(events, start)
+
+	expected := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"SUMMARY:Incident: Backbone down\\; investigating\\, sorry\r\n",
+		"DTSTART:20240301T120000Z\r\nDTEND:20240301T133000Z\r\n",
+		"SUMMARY:Maintenance: Router swap\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VCALENDAR\r\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(calendar, e) {
+			t.Errorf("Calendar is missing %q:\n%s", e, calendar)
+		}
+	}
+	if strings.Count(calendar, "BEGIN:VEVENT") != 2 {
+		t.Errorf("Informational updates should not be in the calendar:\n%s", calendar)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A part of the network that visitors might care about, like "Backbone" or
+// "Website".
+type Component struct {
+	Name        string
+	Description string
+	Group       string
+}
+
+// Components are configured as a semicolon separated list of
+// "Group/Name=Description" entries. The group and description are optional,
+// e.g. "Core/Backbone=Links between hubs;Core/Hubs;Website".
+func parseComponents(value string) []Component {
+	components, problems := readComponents(value)
+	for _, problem := range problems {
+		log.Printf("%s. Skipping it.\n", problem)
+	}
+	return components
+}
+
+// Does the work for parseComponents, and says what was wrong with any
+// entries it had to skip
+func readComponents(value string) (components []Component, problems []string) {
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		var component Component
+		name, description, _ := strings.Cut(entry, "=")
+		component.Description = strings.TrimSpace(description)
+		if group, rest, found := strings.Cut(name, "/"); found {
+			component.Group = strings.TrimSpace(group)
+			name = rest
+		}
+		component.Name = strings.TrimSpace(name)
+		key := normalizeComponentName(component.Name)
+		if key == "" {
+			problems = append(problems, fmt.Sprintf("Component '%s' needs a name", entry))
+			continue
+		}
+		if seen[key] {
+			problems = append(problems, fmt.Sprintf("Component '%s' is listed twice", component.Name))
+			continue
+		}
+		seen[key] = true
+		components = append(components, component)
+	}
+	return components, problems
+}
+
+var hashtagRegex = regexp.MustCompile(`(?:^|\s|\()#([\w-]+)`)
+
+// Squash a name down so "#core-router", "#CoreRouter" and "Core Router" all
+// look the same.
+func normalizeComponentName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+// Finds the configured component with the given name, if there is one
+func findComponent(name string) (Component, bool) {
+	for _, component := range config().Components {
+		if normalizeComponentName(component.Name) == normalizeComponentName(name) {
+			return component, true
+		}
+	}
+	return Component{}, false
+}
+
+// Picks out the #hashtags in a message that name one of our components
+func componentsFromHashtags(message string) (names []string) {
+	for _, match := range hashtagRegex.FindAllStringSubmatch(message, -1) {
+		component, ok := findComponent(match[1])
+		if ok && !stringInSlice(names, component.Name) {
+			names = append(names, component.Name)
+		}
+	}
+	return names
+}
+
+// Records which components an update is about, from wherever they were
+// mentioned, without repeating any.
+func (update *StatusUpdate) tagComponents(names ...string) {
+	for _, name := range names {
+		component, ok := findComponent(name)
+		if ok && !stringInSlice(update.Components, component.Name) {
+			update.Components = append(update.Components, component.Name)
+		}
+	}
+}
+
+// How one component is doing right now
+type ComponentStatus struct {
+	Component
+	Severity    string
+	Label       string
+	BorderClass string
+	Icon        string
+	Uptime      ComponentUptime
+}
+
+type ComponentGroup struct {
+	Name       string
+	Components []ComponentStatus
+}
+
+// The worst severity of any pinned update that mentions the component
+func componentSeverity(name string, pinnedUpdates []StatusUpdate) (severity string) {
+	for _, update := range pinnedUpdates {
+		if stringInSlice(update.Components, name) && severityRank(update.Severity) > severityRank(severity) {
+			severity = update.Severity
+		}
+	}
+	return severity
+}
+
+// Works out the status of every configured component from the worst pinned
+// update that mentions it, grouped the way they were configured.
+func componentStatuses(pinnedUpdates []StatusUpdate) (groups []ComponentGroup) {
+	now := time.Now().In(displayLocation())
+	for _, component := range config().Components {
+		status := ComponentStatus{Component: component}
+		status.Severity = componentSeverity(component.Name, pinnedUpdates)
+		status.Uptime = componentUptime(store.componentHistory(component.Name), now, uptimeDays)
+
+		level, _ := severityLevel(status.Severity)
+		switch level.Impact {
+		case SeverityWarn:
+			status.Label = "Degraded"
+			status.BorderClass = "border-warning"
+			status.Icon = level.Icon
+		case SeverityError:
+			status.Label = "Outage"
+			status.BorderClass = "border-danger"
+			status.Icon = level.Icon
+		default:
+			status.Label = "Operational"
+			status.BorderClass = "border-success"
+			status.Icon = "checkmark.svg"
+		}
+		// Custom levels say what they mean themselves
+		if level.Impact != SeverityOK && level.Name != level.Impact {
+			status.Label = level.Label
+		}
+
+		// Keep groups in the order they first show up
+		found := false
+		for i := range groups {
+			if groups[i].Name == component.Group {
+				groups[i].Components = append(groups[i].Components, status)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, ComponentGroup{Name: component.Group, Components: []ComponentStatus{status}})
+		}
+	}
+	return groups
+}
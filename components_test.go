@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseComponents(t *testing.T) {
+	components := parseComponents("Core/Backbone=Links between hubs; Core/Hubs ;Website;")
+	expected := []Component{
+		{Name: "Backbone", Description: "Links between hubs", Group: "Core"},
+		{Name: "Hubs", Group: "Core"},
+		{Name: "Website"},
+	}
+	if len(components) != len(expected) {
+		t.Fatalf("Expected %d components, got %d: %+v", len(expected), len(components), components)
+	}
+	for i := range expected {
+		if components[i] != expected[i] {
+			t.Errorf("Component %d did not match.\nExpected: '%+v'\nReceived: '%+v'", i, expected[i], components[i])
+		}
+	}
+}
+
+func TestComponentsFromHashtags(t *testing.T) {
+	withConfig(t, func(c *Config) { c.Components = parseComponents("Core/Backbone;Core/Core Router;Website") })
+	hashtagStrings := map[string][]string{
+		"The #backbone is down":                   {"Backbone"},
+		"#website and #core-router are sad":       {"Website", "Core Router"},
+		"#CoreRouter (#backbone) #BACKBONE":       {"Core Router", "Backbone"},
+		"Channel <#C0123|> is not a hashtag":      nil,
+		"Neither is a#backbone or #unknown thing": nil,
+	}
+	for m, expected := range hashtagStrings {
+		names := componentsFromHashtags(m)
+		if len(names) != len(expected) {
+			t.Errorf("Components for '%s' did not match.\nExpected: '%v'\nReceived: '%v'", m, expected, names)
+			continue
+		}
+		for i := range expected {
+			if names[i] != expected[i] {
+				t.Errorf("Components for '%s' did not match.\nExpected: '%v'\nReceived: '%v'", m, expected, names)
+			}
+		}
+	}
+}
+
+func TestComponentStatusesUseWorstSeverity(t *testing.T) {
+	withConfig(t, func(c *Config) { c.Components = parseComponents("Core/Backbone;Core/Hubs;Website") })
+	warn := StatusUpdate{Components: []string{"Backbone", "Hubs"}}
+	warn.setSeverity(SeverityWarn)
+	critical := StatusUpdate{Components: []string{"Backbone"}}
+	critical.setSeverity(SeverityError)
+
+	groups := componentStatuses([]StatusUpdate{warn, critical})
+	if len(groups) != 2 || groups[0].Name != "Core" || groups[1].Name != "" {
+		t.Fatalf("Components were not grouped as configured: %+v", groups)
+	}
+	expected := map[string]string{
+		"Backbone": SeverityError,
+		"Hubs":     SeverityWarn,
+		"Website":  "",
+	}
+	for _, group := range groups {
+		for _, status := range group.Components {
+			if status.Severity != expected[status.Name] {
+				t.Errorf("Expected %s to be '%s', got '%s'", status.Name, expected[status.Name], status.Severity)
+			}
+		}
+	}
+}
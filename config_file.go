@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// A link in the navbar or the footer
+type Link struct {
+	Label string
+	URL   string
+}
+
+// One column of links in the footer
+type FooterSection struct {
+	Title string
+	Links []Link
+}
+
+// Settings from a config file, keyed by the environment variable they stand
+// in for. The environment still wins, so secrets can stay out of the file.
+type configSettings map[string]string
+
+// Reads a setting from the environment, or the config file if it isn't set
+// there
+func (settings configSettings) lookup(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	value, ok := settings[key]
+	return value, ok
+}
+
+func (settings configSettings) get(key string) string {
+	value, _ := settings.lookup(key)
+	return value
+}
+
+// Reads a setting, falling back to a default if it's unset
+func (settings configSettings) getDefault(key string, fallback string) string {
+	if value, ok := settings.lookup(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// The settings from CSP_CONFIG_FILE that the running config was loaded with
+var fileSettings configSettings
+
+func getenv(key string) string {
+	return fileSettings.get(key)
+}
+
+func getenvDefault(key string, fallback string) string {
+	return fileSettings.getDefault(key, fallback)
+}
+
+// Everything the config file can hold. Most of it is the same settings as the
+// environment, nested at the underscores and without the CSP_ prefix, so
+// slack.access_token and slack_access_token are both CSP_SLACK_ACCESS_TOKEN.
+type ConfigFile struct {
+	Settings       configSettings
+	NavLinks       []Link
+	FooterSections []FooterSection
+}
+
+// Lists of settings in the file get turned into the same format the
+// environment uses for them, so there's only one place that parses them
+var fileLists = map[string][]string{
+	"CSP_SEVERITY_LEVELS":       {"name", "label", "impact", "emoji", "discord_emoji", "color", "icon"},
+	"CSP_COMPONENTS":            {"group", "name", "description"},
+	"CSP_RECURRING_MAINTENANCE": {"title", "rule", "duration", "components", "description"},
+	"CSP_SLACK_STATUS_CHANNEL":  {"id", "component"},
+}
+
+// Reads a YAML or TOML config file, depending on its extension. No path means
+// no file.
+func loadConfigFile(path string) (file ConfigFile, err error) {
+	file.Settings = make(configSettings)
+	if path == "" {
+		return file, nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return file, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	raw := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, &raw)
+	case ".toml":
+		err = toml.Unmarshal(contents, &raw)
+	default:
+		return file, fmt.Errorf("config file %s should end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return file, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	if links, ok := raw["nav_links"]; ok {
+		delete(raw, "nav_links")
+		file.NavLinks, err = parseFileLinks("nav_links", links)
+		if err != nil {
+			return file, err
+		}
+	}
+	if sections, ok := raw["footer"]; ok {
+		delete(raw, "footer")
+		file.FooterSections, err = parseFileFooter(sections)
+		if err != nil {
+			return file, err
+		}
+	}
+	err = flattenSettings(file.Settings, "CSP", raw)
+	return file, err
+}
+
+// Walks the nested settings down to CSP_* keys
+func flattenSettings(settings map[string]string, key string, value any) error {
+	switch value := value.(type) {
+	case map[string]any:
+		for name, child := range value {
+			err := flattenSettings(settings, key+"_"+strings.ToUpper(name), child)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		joined, err := joinFileList(key, value)
+		if err != nil {
+			return err
+		}
+		settings[key] = joined
+	case nil:
+		// Left blank, so it's as good as unset
+	default:
+		settings[key] = fmt.Sprint(value)
+	}
+	return nil
+}
+
+// Lists of plain values are comma separated, like CSP_SOURCES. Lists of
+// tables only make sense for the settings in fileLists.
+func joinFileList(key string, list []any) (string, error) {
+	fields := fileListFields(key)
+	var entries []string
+	for _, item := range list {
+		table, isTable := item.(map[string]any)
+		if !isTable {
+			entries = append(entries, fmt.Sprint(item))
+			continue
+		}
+		if fields == nil {
+			return "", fmt.Errorf("%s can't be a list of tables", fileKey(key))
+		}
+		values, err := tableFields(fileKey(key), table, fields...)
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, formatFileEntry(key, values))
+	}
+
+	switch key {
+	case "CSP_SEVERITY_LEVELS", "CSP_RECURRING_MAINTENANCE":
+		return strings.Join(entries, "\n"), nil
+	case "CSP_COMPONENTS":
+		return strings.Join(entries, ";"), nil
+	}
+	return strings.Join(entries, ","), nil
+}
+
+func fileListFields(key string) []string {
+	// Extra Slack workspaces have status channels too
+	if strings.HasPrefix(key, "CSP_SLACK_") && strings.HasSuffix(key, "_STATUS_CHANNEL") {
+		return fileLists["CSP_SLACK_STATUS_CHANNEL"]
+	}
+	return fileLists[key]
+}
+
+// Puts one table back together the way it would be written in the
+// environment
+func formatFileEntry(key string, values map[string]string) string {
+	switch key {
+	case "CSP_SEVERITY_LEVELS":
+		return strings.Join([]string{values["name"], values["label"], values["impact"], values["emoji"], values["discord_emoji"], values["color"], values["icon"]}, "|")
+	case "CSP_COMPONENTS":
+		entry := values["name"]
+		if values["group"] != "" {
+			entry = values["group"] + "/" + entry
+		}
+		if values["description"] != "" {
+			entry += "=" + values["description"]
+		}
+		return entry
+	case "CSP_RECURRING_MAINTENANCE":
+		return strings.Join([]string{values["title"], values["rule"], values["duration"], values["components"], values["description"]}, "|")
+	}
+	if values["component"] == "" {
+		return values["id"]
+	}
+	return values["id"] + ":" + values["component"]
+}
+
+// Pulls the named fields out of a table, complaining about anything else in
+// it so typos don't go unnoticed. Lists become comma separated.
+func tableFields(where string, table map[string]any, names ...string) (map[string]string, error) {
+	values := make(map[string]string)
+	for name, value := range table {
+		if !stringInSlice(names, name) {
+			return nil, fmt.Errorf("%s has an unknown field '%s'. Expected one of %s", where, name, strings.Join(names, ", "))
+		}
+		if list, ok := value.([]any); ok {
+			var items []string
+			for _, item := range list {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[name] = strings.Join(items, ",")
+		} else if value != nil {
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return values, nil
+}
+
+func parseFileLinks(where string, value any) (links []Link, err error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s should be a list of links", where)
+	}
+	for _, item := range list {
+		table, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s should be a list of links with a label and a url", where)
+		}
+		values, err := tableFields(where, table, "label", "url")
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, Link{Label: values["label"], URL: values["url"]})
+	}
+	return links, nil
+}
+
+func parseFileFooter(value any) (sections []FooterSection, err error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("footer should be a list of sections")
+	}
+	for _, item := range list {
+		table, ok := item.(map[string]any)
+		if !ok {
+			return nil, errors.New("footer should be a list of sections with a title and links")
+		}
+		section := FooterSection{}
+		for name, value := range table {
+			switch name {
+			case "title":
+				section.Title = fmt.Sprint(value)
+			case "links":
+				section.Links, err = parseFileLinks("footer links", value)
+				if err != nil {
+					return nil, err
+				}
+			default:
+				return nil, fmt.Errorf("footer has an unknown field '%s'. Expected title or links", name)
+			}
+		}
+		sections = append(sections, section)
+	}
+	return sections, nil
+}
+
+// How a setting is spelled in the file, for error messages
+func fileKey(key string) string {
+	return strings.ToLower(strings.TrimPrefix(key, "CSP_"))
+}
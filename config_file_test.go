@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name string, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(contents), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+org_name: NYC Mesh
+sources: [slack, discord]
+drafts: true
+slack:
+  access_token: xoxb-file
+  truncation: 20
+  status_channel:
+    - id: C0123
+      component: Backbone
+    - id: C0456
+  backbone:
+    status_channel: [{id: C0789}]
+severity_levels:
+  - {name: ok, label: OK, impact: ok}
+  - {name: degraded, label: Degraded, impact: warn, emoji: warning, color: "#fff3cd"}
+components:
+  - {group: Core, name: Backbone, description: Links between hubs}
+  - {name: Website}
+nav_links:
+  - {label: Map, url: https://example.com/map}
+footer:
+  - title: Community
+    links:
+      - {label: Chat, url: https://example.com/chat}
+`)
+	file, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]string{
+		"CSP_ORG_NAME":                      "NYC Mesh",
+		"CSP_SOURCES":                       "slack,discord",
+		"CSP_DRAFTS":                        "true",
+		"CSP_SLACK_ACCESS_TOKEN":            "xoxb-file",
+		"CSP_SLACK_TRUNCATION":              "20",
+		"CSP_SLACK_STATUS_CHANNEL":          "C0123:Backbone,C0456",
+		"CSP_SLACK_BACKBONE_STATUS_CHANNEL": "C0789",
+		"CSP_SEVERITY_LEVELS":               "ok|OK|ok||||\ndegraded|Degraded|warn|warning||#fff3cd|",
+		"CSP_COMPONENTS":                    "Core/Backbone=Links between hubs;Website",
+	} {
+		if file.Settings[key] != expected {
+			t.Errorf("Expected %s to be %q, got %q", key, expected, file.Settings[key])
+		}
+	}
+
+	levels := parseSeverityLevels(file.Settings["CSP_SEVERITY_LEVELS"])
+	if len(levels) != 2 || levels[1].Color != "#fff3cd" {
+		t.Errorf("Severity levels from the file didn't parse: %+v", levels)
+	}
+	if len(file.NavLinks) != 1 || file.NavLinks[0].URL != "https://example.com/map" {
+		t.Errorf("Unexpected nav links %+v", file.NavLinks)
+	}
+	if len(file.FooterSections) != 1 || file.FooterSections[0].Title != "Community" || len(file.FooterSections[0].Links) != 1 {
+		t.Errorf("Unexpected footer %+v", file.FooterSections)
+	}
+}
+
+func TestLoadConfigFileTOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+reminder_schedule = "0 17 * * *"
+
+[discord]
+token = "from-file"
+
+[[components]]
+name = "Hubs"
+`)
+	file, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Settings["CSP_DISCORD_TOKEN"] != "from-file" || file.Settings["CSP_REMINDER_SCHEDULE"] != "0 17 * * *" || file.Settings["CSP_COMPONENTS"] != "Hubs" {
+		t.Errorf("Unexpected settings %v", file.Settings)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	for name, contents := range map[string]string{
+		"typo.yaml":   "components:\n  - {name: Hubs, descripton: oops}\n",
+		"tables.yaml": "sources:\n  - {name: slack}\n",
+		"broken.toml": "[discord\n",
+		"config.json": "{}",
+	} {
+		_, err := loadConfigFile(writeConfigFile(t, name, contents))
+		if err == nil {
+			t.Errorf("Expected %s to fail to load", name)
+		}
+	}
+}
+
+func TestEnvironmentOverridesFile(t *testing.T) {
+	defer func(settings map[string]string) { fileSettings = settings }(fileSettings)
+	fileSettings = map[string]string{"CSP_ORG_NAME": "From the file", "CSP_LOGO_URL": "logo.png"}
+	unsetenv(t, "CSP_LOGO_URL", "CSP_PIN_EMOJI")
+	t.Setenv("CSP_ORG_NAME", "From the environment")
+
+	if name := getenv("CSP_ORG_NAME"); name != "From the environment" {
+		t.Errorf("Expected the environment to win, got %q", name)
+	}
+	if logo := getenv("CSP_LOGO_URL"); logo != "logo.png" {
+		t.Errorf("Expected the file to fill in the rest, got %q", logo)
+	}
+	if pin := getenvDefault("CSP_PIN_EMOJI", "pushpin"); pin != "pushpin" {
+		t.Errorf("Expected the default when neither sets it, got %q", pin)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gin-gonic/gin"
+)
+
+type CSPDiscord struct {
+	session *discordgo.Session
+	botID   string
+
+	// Messages fetched over REST don't say which server they're from
+	guildID string
+	label   string
+
+	channelHistory []*discordgo.Message
+
+	// Discord doesn't send us the state of the select menu along with the
+	// button press, so remember what was picked in each prompt.
+	promptOptions map[string][]string
+
+	page *CSPPage
+}
+
+func NewCSPDiscord() (app CSPDiscord, err error) {
+	c := config()
+	app.page = &CSPPage{}
+	app.session, err = discordgo.New("Bot " + c.DiscordToken)
+	if err != nil {
+		return app, err
+	}
+	app.session.Identify.Intents = discordgo.IntentsGuildMessages |
+		discordgo.IntentsGuildMessageReactions |
+		discordgo.IntentsMessageContent
+	// Handle one event at a time, like we do with Slack, so that a page
+	// rebuild never races another one.
+	app.session.SyncEvents = true
+	app.promptOptions = make(map[string][]string)
+
+	// Get some deets we'll need from the Discord API
+	botUser, err := app.session.User("@me")
+	if err != nil {
+		return app, err
+	}
+	app.botID = botUser.ID
+
+	statusChannel, err := app.session.Channel(c.DiscordStatusChannelID)
+	if err != nil {
+		return app, err
+	}
+	app.guildID = statusChannel.GuildID
+
+	app.label = c.DiscordLabel
+	if app.label == "" {
+		guild, err := app.session.Guild(app.guildID)
+		if err != nil {
+			return app, err
+		}
+		app.label = guild.Name
+	}
+
+	// Get the channel history
+	err = app.getChannelHistory()
+	if err != nil {
+		return app, err
+	}
+
+	// Initialize the actual data we need for the status page
+	err = app.BuildStatusPage()
+	if err != nil {
+		return app, err
+	}
+	return app, nil
+}
+
+// Nuke the old slices and re-build them
+func (app *CSPDiscord) BuildStatusPage() (err error) {
+	log.Println("Building Status Page...")
+	updates := make([]StatusUpdate, 0)
+	pinnedUpdates := make([]StatusUpdate, 0)
+	for _, message := range app.channelHistory {
+		// Ignore messages that don't mention us. Also, ignore messages that
+		// mention us but are empty!
+		if !discordBotActionablyMentioned(message.Content, app.botID) {
+			continue
+		}
+
+		var update StatusUpdate
+		update.HTML = MarkdownToHTML(app.discordMentionsToMarkdown(message))
+		update.ID = message.ID
+		update.SentBy = app.resolveDisplayName(message)
+		update.tagComponents(componentsFromHashtags(message.Content)...)
+		update.Origin = app.label
+		update.Time = message.Timestamp
+
+		update.setSeverity(GetDiscordMessageStatus(message.Reactions))
+
+		if message.Pinned {
+			pinnedUpdates = append(pinnedUpdates, update)
+		} else {
+			updates = append(updates, update)
+		}
+	}
+
+	recordUnpinned(app.page.setUpdates(pinnedUpdates, updates), time.Now())
+	return nil
+}
+
+// Pass-Thru the interface to the Page object
+func (app *CSPDiscord) StatusPage(gin *gin.Context) {
+	app.page.statusPage(gin)
+}
+
+func (app *CSPDiscord) Page() *CSPPage {
+	return app.page
+}
+
+func (app *CSPDiscord) SendReminders(now bool) error {
+	fmt.Println("Sending unpin reminders...")
+	var pinnedMessageLinks []ReminderInfo
+	for _, message := range app.channelHistory {
+		// Don't send reminders for messages that don't mention the bot.
+		// That way, we can still pin messages.
+		if !discordBotActionablyMentioned(message.Content, app.botID) || !message.Pinned {
+			continue
+		}
+
+		// Don't bother if the message hasn't been up long enough for its
+		// severity
+		status := GetDiscordMessageStatus(message.Reactions)
+		if !reminderDue(status, time.Since(message.Timestamp), now) {
+			fmt.Println("Message not pinned for long enough. Ignoring.")
+			continue
+		}
+
+		link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", app.guildID, message.ChannelID, message.ID)
+		pinnedMessageLinks = append(pinnedMessageLinks, ReminderInfo{message.Author.ID, link, message.Timestamp, status, message.ID})
+		fmt.Println("Found message.")
+	}
+
+	if len(pinnedMessageLinks) == 0 {
+		fmt.Println("No messages pinned.")
+		return nil
+	}
+
+	// Send summary message
+	summaryMessage := fmt.Sprintln("Hello, Admins.\nThe following messages are currently pinned.")
+	for _, m := range pinnedMessageLinks {
+		parsedStatus := discordSeverityEmoji(m.status)
+		if parsedStatus == "" {
+			parsedStatus = "•"
+		}
+		// Discord shows these in whatever time zone the reader is in
+		summaryMessage += fmt.Sprintf("%s <@%s> Since <t:%d:f>: %s\n\n", parsedStatus, m.userID, m.at.Unix(), m.link)
+	}
+
+	summaryMessage += fmt.Sprintf("It might be time to unpin them if they are no longer relevant.")
+
+	_, err := app.session.ChannelMessageSend(config().DiscordStatusChannelID, summaryMessage)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("success.")
+	return nil
+}
+
+// Posts a message from us in the status channel
+func (app *CSPDiscord) Announce(message string) error {
+	_, err := app.session.ChannelMessageSend(config().DiscordStatusChannelID, message)
+	return err
+}
+
+// Takes an update off the page and says why underneath it
+func (app *CSPDiscord) Unpin(updateID string, note string) error {
+	c := config()
+	err := app.session.ChannelMessageUnpin(c.DiscordStatusChannelID, updateID)
+	if err != nil {
+		return err
+	}
+	_, err = app.session.ChannelMessageSendReply(c.DiscordStatusChannelID, note, &discordgo.MessageReference{
+		MessageID: updateID,
+		ChannelID: c.DiscordStatusChannelID,
+	})
+	return err
+}
+
+func (app *CSPDiscord) Run() {
+	h := CSPDiscordEvtHandler{app}
+	app.session.AddHandler(h.handleReady)
+	app.session.AddHandler(h.handleMessageCreate)
+	app.session.AddHandler(h.handleMessageUpdate)
+	app.session.AddHandler(h.handleMessageDelete)
+	app.session.AddHandler(h.handleReactionAdd)
+	app.session.AddHandler(h.handleReactionRemove)
+	app.session.AddHandler(h.handleChannelPinsUpdate)
+	app.session.AddHandler(h.handleInteractionCreate)
+
+	fmt.Println("Connecting to Discord...")
+	err := app.session.Open()
+	if err != nil {
+		log.Fatalf("Could not connect to Discord: %s", err)
+	}
+	select {}
+}
+
+// Utility functions
+
+// Sync our cached Discord messages and re-build the page history
+func (app *CSPDiscord) refresh() {
+	err := app.getChannelHistory()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	err = app.BuildStatusPage()
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+func (app *CSPDiscord) getChannelHistory() (err error) {
+	c := config()
+	log.Println("Fetching channel history from: ", c.DiscordStatusChannelID)
+	limit, _ := strconv.Atoi(c.DiscordTruncation)
+	history, err := app.session.ChannelMessages(c.DiscordStatusChannelID, limit, "", "", "")
+	if err != nil {
+		return err
+	}
+	app.channelHistory = history
+	return nil
+}
+
+// Replaces channel and user mentions with something a human can read, and
+// drops the mention of our bot.
+func (app *CSPDiscord) discordMentionsToMarkdown(message *discordgo.Message) string {
+	content := stripDiscordBotMention(message.Content, app.botID)
+	for _, user := range message.Mentions {
+		name := user.Username
+		if user.GlobalName != "" {
+			name = user.GlobalName
+		}
+		content = strings.NewReplacer(
+			fmt.Sprintf("<@%s>", user.ID), "@"+name,
+			fmt.Sprintf("<@!%s>", user.ID), "@"+name,
+		).Replace(content)
+	}
+
+	return discordChannelRegex.ReplaceAllStringFunc(content, func(mention string) string {
+		channelID := discordChannelRegex.FindStringSubmatch(mention)[1]
+		channel, err := app.session.Channel(channelID)
+		if err != nil {
+			log.Println("Error: Did not get channel name for channel ", channelID)
+			return "#unknown"
+		}
+		return fmt.Sprintf("[#%s](https://discord.com/channels/%s/%s)", channel.Name, channel.GuildID, channel.ID)
+	})
+}
+
+// Prefer the name people actually see in the server over the account name
+func (app *CSPDiscord) resolveDisplayName(message *discordgo.Message) string {
+	member, err := app.session.GuildMember(app.guildID, message.Author.ID)
+	if err == nil && member.Nick != "" {
+		return member.Nick
+	}
+	if message.Author.GlobalName != "" {
+		return message.Author.GlobalName
+	}
+	return message.Author.Username
+}
+
+func (app *CSPDiscord) clearReactions(messageID string, focusReactions []string) error {
+	for _, reaction := range focusReactions {
+		err := app.session.MessageReactionRemove(config().DiscordStatusChannelID, messageID, reaction, "@me")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
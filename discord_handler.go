@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type CSPDiscordEvtHandler struct {
+	*CSPDiscord
+}
+
+func (h *CSPDiscordEvtHandler) handleReady(s *discordgo.Session, r *discordgo.Ready) {
+	fmt.Println("Connected to Discord.")
+}
+
+func (h *CSPDiscordEvtHandler) handleMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	c := config()
+	if m.ChannelID != c.DiscordStatusChannelID || m.Author.ID == h.botID {
+		return
+	}
+
+	// If the bot was mentioned in this message, then we should probably
+	// re-build the page, and if not, we should bail.
+	if !discordBotActionablyMentioned(m.Content, h.botID) {
+		return
+	}
+	defer h.refresh()
+
+	log.Printf("Got mentioned. Message ID is: %s\n", m.ID)
+
+	channel, err := s.Channel(c.DiscordForwardChannelID)
+	if err != nil {
+		log.Printf("Could not resolve channel name: %s\n", err)
+		return
+	}
+	_, err = s.ChannelMessageSendComplex(c.DiscordStatusChannelID, CreateDiscordUpdateResponseMsg(channel.Name, m.Author.ID, m.ID))
+	if err != nil {
+		log.Printf("Error posting prompt message: %s", err)
+	}
+}
+
+func (h *CSPDiscordEvtHandler) handleMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	if m.ChannelID != config().DiscordStatusChannelID {
+		return
+	}
+	h.refresh()
+}
+
+func (h *CSPDiscordEvtHandler) handleMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	if m.ChannelID != config().DiscordStatusChannelID {
+		return
+	}
+	delete(h.promptOptions, m.ID)
+	h.refresh()
+}
+
+func (h *CSPDiscordEvtHandler) handleChannelPinsUpdate(s *discordgo.Session, p *discordgo.ChannelPinsUpdate) {
+	if p.ChannelID != config().DiscordStatusChannelID {
+		return
+	}
+	h.refresh()
+}
+
+func (h *CSPDiscordEvtHandler) handleReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	c := config()
+	reaction := r.Emoji.Name
+	if r.ChannelID != c.DiscordStatusChannelID || r.UserID == h.botID || !isRelevantDiscordReaction(reaction) {
+		return
+	}
+	message, err := s.ChannelMessage(r.ChannelID, r.MessageID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if !discordBotActionablyMentioned(message.Content, h.botID) {
+		return
+	}
+
+	// If necessary, remove a conflicting reaction
+	h.clearReactions(
+		r.MessageID,
+		discordSeverityEmojis(),
+	)
+	// Mirror the reaction on the message
+	err = s.MessageReactionAdd(r.ChannelID, r.MessageID, reaction)
+	if err != nil {
+		log.Println(err)
+	}
+	h.refresh()
+}
+
+func (h *CSPDiscordEvtHandler) handleReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove) {
+	c := config()
+	reaction := r.Emoji.Name
+	if r.ChannelID != c.DiscordStatusChannelID || r.UserID == h.botID || !isRelevantDiscordReaction(reaction) {
+		return
+	}
+	message, err := s.ChannelMessage(r.ChannelID, r.MessageID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if !discordBotActionablyMentioned(message.Content, h.botID) {
+		return
+	}
+
+	// Take our mirrored reaction off too
+	err = s.MessageReactionRemove(r.ChannelID, r.MessageID, reaction, "@me")
+	if err != nil {
+		log.Println(err)
+	}
+	h.refresh()
+}
+
+func (h *CSPDiscordEvtHandler) handleInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		log.Println("no handler for event of given type")
+		return
+	}
+
+	// Acknowledge the interaction; we'll deal with the prompt ourselves.
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	data := i.MessageComponentData()
+	switch data.CustomID {
+	case CSPDiscordOptions:
+		h.promptOptions[i.Message.ID] = data.Values
+	case CSPCancel:
+		h.handlePromptInteraction(i, data.CustomID)
+	case CSPDiscordSeverity:
+		if len(data.Values) > 0 {
+			h.handlePromptInteraction(i, data.Values[0])
+		}
+	default:
+		if strings.HasPrefix(data.CustomID, CSPSetSeverityPrefix) {
+			h.handlePromptInteraction(i, data.CustomID)
+		}
+	}
+}
+
+func (h *CSPDiscordEvtHandler) handlePromptInteraction(i *discordgo.InteractionCreate, actionID string) {
+	c := config()
+	log.Printf("Component Action Detected: %s\n", actionID)
+	if i.Message.MessageReference == nil {
+		log.Println("Prompt does not reference a status update")
+		return
+	}
+	messageID := i.Message.MessageReference.MessageID
+
+	for _, option := range h.promptOptions[i.Message.ID] {
+		switch option {
+		case CSPPin:
+			log.Println("Will pin message")
+
+			err := h.session.ChannelMessagePin(c.DiscordStatusChannelID, messageID)
+			if err != nil {
+				log.Println(err)
+			}
+		case CSPForward:
+			log.Println("Will forward message")
+
+			message, err := h.session.ChannelMessage(c.DiscordStatusChannelID, messageID)
+			if err != nil {
+				log.Println(err)
+				break
+			}
+
+			_, err = h.session.ChannelMessageSend(c.DiscordForwardChannelID, stripDiscordBotMention(message.Content, h.botID))
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	delete(h.promptOptions, i.Message.ID)
+
+	if severity, ok := strings.CutPrefix(actionID, CSPSetSeverityPrefix); ok {
+		if _, known := severityLevel(severity); known {
+			// Clear any old reactions, then add the reaction we want
+			h.clearReactions(
+				messageID,
+				discordSeverityEmojis(),
+			)
+			err := h.session.MessageReactionAdd(c.DiscordStatusChannelID, messageID, discordSeverityEmoji(severity))
+			if err != nil {
+				log.Printf("Error adding reaction: %v", err)
+			}
+		} else {
+			log.Printf("Unknown severity level '%s', leaving the reactions alone\n", severity)
+		}
+	}
+
+	err := h.session.ChannelMessageDelete(c.DiscordStatusChannelID, i.Message.ID)
+	if err != nil {
+		log.Println(err)
+	}
+	h.refresh()
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// A tiny stand-in for the Discord API that serves a fixed channel and records
+// what the bot asks it to do. It sits under the session's HTTP client, since
+// discordgo always talks to discord.com.
+type fakeDiscord struct {
+	mu       sync.Mutex
+	messages []*discordgo.Message
+	requests []string
+}
+
+func (f *fakeDiscord) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/api/v"+discordgo.APIVersion)
+	f.requests = append(f.requests, r.Method+" "+path)
+
+	var body any
+	switch {
+	case r.Method == http.MethodGet && path == "/channels/forward":
+		body = discordgo.Channel{ID: "forward", Name: "announcements"}
+	case r.Method == http.MethodGet && path == "/channels/status/messages":
+		body = f.messages
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/channels/status/messages/"):
+		id := strings.TrimPrefix(path, "/channels/status/messages/")
+		for _, message := range f.messages {
+			if message.ID == id {
+				body = message
+			}
+		}
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/guilds/guild/members/"):
+		body = discordgo.Member{Nick: "Some Volunteer"}
+	case r.Method == http.MethodPost && path == "/channels/status/messages":
+		body = discordgo.Message{ID: "prompt", ChannelID: "status"}
+	}
+
+	response := &http.Response{StatusCode: http.StatusNoContent, Header: make(http.Header), Body: io.NopCloser(strings.NewReader("")), Request: r}
+	if body != nil {
+		raw, _ := json.Marshal(body)
+		response.StatusCode = http.StatusOK
+		response.Header.Set("Content-Type", "application/json")
+		response.Body = io.NopCloser(strings.NewReader(string(raw)))
+	}
+	return response, nil
+}
+
+func (f *fakeDiscord) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func newTestCSPDiscord(t *testing.T, fake *fakeDiscord) *CSPDiscordEvtHandler {
+	withConfig(t, func(c *Config) {
+		c.DiscordStatusChannelID = "status"
+		c.DiscordForwardChannelID = "forward"
+		c.SeverityLevels = parseSeverityLevels("ok|OK/Info|ok|white_check_mark|✅\nwarn|Warning|warn|warning|⚠️\nerror|Critical|error|fire|🔥")
+	})
+	session, err := discordgo.New("Bot token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.Client = &http.Client{Transport: fake}
+	app := &CSPDiscord{
+		session:       session,
+		botID:         "bot",
+		guildID:       "guild",
+		label:         "Mesh",
+		promptOptions: make(map[string][]string),
+		page:          &CSPPage{},
+	}
+	return &CSPDiscordEvtHandler{app}
+}
+
+func discordMessage(id string, content string) *discordgo.Message {
+	return &discordgo.Message{
+		ID:        id,
+		ChannelID: "status",
+		Content:   content,
+		Author:    &discordgo.User{ID: "u1", Username: "volunteer"},
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestDiscordPromptsOnMention(t *testing.T) {
+	update := discordMessage("m1", "<@bot> the backbone is down")
+	update.Pinned = true
+	fake := &fakeDiscord{messages: []*discordgo.Message{update, discordMessage("m2", "just chatting")}}
+	h := newTestCSPDiscord(t, fake)
+
+	h.handleMessageCreate(h.session, &discordgo.MessageCreate{Message: discordMessage("m2", "just chatting")})
+	if requests := fake.sent(); len(requests) > 0 {
+		t.Errorf("Expected messages that don't mention us to be ignored, got %v", requests)
+	}
+
+	h.handleMessageCreate(h.session, &discordgo.MessageCreate{Message: update})
+	requests := fake.sent()
+	if !stringInSlice(requests, "POST /channels/status/messages") {
+		t.Errorf("Expected a prompt to be posted, got %v", requests)
+	}
+	if len(h.page.pinnedUpdates) != 1 || h.page.pinnedUpdates[0].SentBy != "Some Volunteer" {
+		t.Errorf("Expected the page to be rebuilt with the pinned update, got %+v", h.page.pinnedUpdates)
+	}
+}
+
+func TestDiscordSeverityButton(t *testing.T) {
+	fake := &fakeDiscord{messages: []*discordgo.Message{discordMessage("m1", "<@bot> the backbone is down")}}
+	h := newTestCSPDiscord(t, fake)
+	h.promptOptions["prompt"] = []string{CSPPin}
+
+	h.handleInteractionCreate(h.session, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:    "i1",
+		Token: "token",
+		Type:  discordgo.InteractionMessageComponent,
+		Data:  discordgo.MessageComponentInteractionData{CustomID: CSPSetSeverityPrefix + SeverityError},
+		Message: &discordgo.Message{
+			ID:               "prompt",
+			MessageReference: &discordgo.MessageReference{MessageID: "m1"},
+		},
+	}})
+
+	requests := fake.sent()
+	for _, expected := range []string{
+		"PUT /channels/status/pins/m1",
+		"PUT /channels/status/messages/m1/reactions/🔥/@me",
+		"DELETE /channels/status/messages/prompt",
+	} {
+		if !stringInSlice(requests, expected) {
+			t.Errorf("Expected %q, got %v", expected, requests)
+		}
+	}
+	if _, ok := h.promptOptions["prompt"]; ok {
+		t.Error("Expected the prompt's options to be forgotten")
+	}
+}
+
+func TestDiscordUnknownSeverityButton(t *testing.T) {
+	fake := &fakeDiscord{messages: []*discordgo.Message{discordMessage("m1", "<@bot> the backbone is down")}}
+	h := newTestCSPDiscord(t, fake)
+
+	// A button from before the levels were reloaded
+	h.handleInteractionCreate(h.session, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:    "i1",
+		Token: "token",
+		Type:  discordgo.InteractionMessageComponent,
+		Data:  discordgo.MessageComponentInteractionData{CustomID: CSPSetSeverityPrefix + "meltdown"},
+		Message: &discordgo.Message{
+			ID:               "prompt",
+			MessageReference: &discordgo.MessageReference{MessageID: "m1"},
+		},
+	}})
+
+	for _, request := range fake.sent() {
+		if strings.Contains(request, "/reactions/") {
+			t.Errorf("Expected the reactions to be left alone, got %s", request)
+		}
+	}
+}
+
+func TestDiscordPromptRowLimit(t *testing.T) {
+	fake := &fakeDiscord{messages: []*discordgo.Message{discordMessage("m1", "<@bot> the backbone is down")}}
+	h := newTestCSPDiscord(t, fake)
+	levels := "meltdown|Meltdown|error|fire|🔥\n"
+	for i := 0; i < 29; i++ {
+		levels += fmt.Sprintf("level%d|Level %d|warn\n", i, i)
+	}
+	withConfig(t, func(c *Config) { c.SeverityLevels = parseSeverityLevels(levels) })
+
+	message := CreateDiscordUpdateResponseMsg("forward", "u1", "m1")
+	if len(message.Components) > 5 {
+		t.Errorf("Discord only takes 5 rows, got %d", len(message.Components))
+	}
+	row := message.Components[1].(discordgo.ActionsRow)
+	menu, ok := row.Components[0].(discordgo.SelectMenu)
+	if !ok || menu.CustomID != CSPDiscordSeverity || len(menu.Options) != 25 {
+		t.Errorf("Expected a severity menu with 25 options, got %+v", row.Components[0])
+	}
+
+	h.handleInteractionCreate(h.session, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		ID:    "i1",
+		Token: "token",
+		Type:  discordgo.InteractionMessageComponent,
+		Data:  discordgo.MessageComponentInteractionData{CustomID: CSPDiscordSeverity, Values: []string{CSPSetSeverityPrefix + "meltdown"}},
+		Message: &discordgo.Message{
+			ID:               "prompt",
+			MessageReference: &discordgo.MessageReference{MessageID: "m1"},
+		},
+	}})
+	if requests := fake.sent(); !stringInSlice(requests, "PUT /channels/status/messages/m1/reactions/🔥/@me") {
+		t.Errorf("Expected picking from the menu to set the severity, got %v", requests)
+	}
+}
+
+func TestDiscordReactionsMirrored(t *testing.T) {
+	fake := &fakeDiscord{messages: []*discordgo.Message{
+		discordMessage("m1", "<@bot> the backbone is down"),
+		discordMessage("m2", "just chatting"),
+	}}
+	h := newTestCSPDiscord(t, fake)
+
+	reaction := func(messageID string, emoji string) *discordgo.MessageReaction {
+		return &discordgo.MessageReaction{UserID: "u1", MessageID: messageID, ChannelID: "status", Emoji: discordgo.Emoji{Name: emoji}}
+	}
+
+	h.handleReactionAdd(h.session, &discordgo.MessageReactionAdd{MessageReaction: reaction("m1", "⚠️")})
+	requests := fake.sent()
+	if !stringInSlice(requests, "DELETE /channels/status/messages/m1/reactions/🔥/@me") || !stringInSlice(requests, "PUT /channels/status/messages/m1/reactions/⚠️/@me") {
+		t.Errorf("Expected the old reactions cleared and the new one mirrored, got %v", requests)
+	}
+
+	for _, r := range []*discordgo.MessageReaction{reaction("m1", "🎉"), reaction("m2", "⚠️")} {
+		h.handleReactionRemove(h.session, &discordgo.MessageReactionRemove{MessageReaction: r})
+		for _, request := range fake.sent() {
+			if strings.HasPrefix(request, "DELETE") {
+				t.Errorf("Expected removing %s from %s to leave our reactions alone, got %s", r.Emoji.Name, r.MessageID, request)
+			}
+		}
+	}
+
+	h.handleReactionRemove(h.session, &discordgo.MessageReactionRemove{MessageReaction: reaction("m1", "⚠️")})
+	if requests := fake.sent(); !stringInSlice(requests, "DELETE /channels/status/messages/m1/reactions/⚠️/@me") {
+		t.Errorf("Expected our mirrored reaction to be removed, got %v", requests)
+	}
+}
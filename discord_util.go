@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// Custom ID of the select menu holding the pin/forward options
+	CSPDiscordOptions = "csp_options"
+	// Custom ID of the select menu we use for severity when there are too
+	// many levels for buttons
+	CSPDiscordSeverity = "csp_severity"
+)
+
+// Discord takes up to five rows of components in a message, five buttons to
+// a row, and 25 options in a select menu. The options menu takes one row.
+const (
+	discordMaxButtonRows    = 4
+	discordMaxSelectOptions = 25
+)
+
+var discordChannelRegex = regexp.MustCompile(`<#([0-9]+)>`)
+
+// Discord mentions come in two flavors depending on whether the user has a
+// nickname set in the server.
+func discordBotMentions(botID string) []string {
+	return []string{
+		fmt.Sprintf("<@%s>", botID),
+		fmt.Sprintf("<@!%s>", botID),
+	}
+}
+
+func stripDiscordBotMention(message string, botID string) string {
+	for _, mention := range discordBotMentions(botID) {
+		message = strings.Replace(message, mention, "", -1)
+	}
+	return strings.TrimSpace(message)
+}
+
+// Ignore messages that don't mention us. Also, ignore messages that
+// mention us but are empty!
+func discordBotActionablyMentioned(message string, botID string) bool {
+	for _, mention := range discordBotMentions(botID) {
+		if strings.Contains(message, mention) {
+			return stripDiscordBotMention(message, botID) != ""
+		}
+	}
+	return false
+}
+
+func isRelevantDiscordReaction(reaction string) bool {
+	return discordEmojiSeverity(reaction) != ""
+}
+
+func GetDiscordMessageStatus(reactions []*discordgo.MessageReactions) string {
+	for _, reaction := range reactions {
+		// Only take action on our reactions
+		if !reaction.Me {
+			continue
+		}
+
+		// Use the first reaction sent by the bot that we find
+		if severity := discordEmojiSeverity(reaction.Emoji.Name); severity != "" {
+			return severity
+		}
+	}
+	return ""
+}
+
+// Function to build the message the bot sends in response to being pinged with
+// a new status update.
+func CreateDiscordUpdateResponseMsg(channelName string, user string, messageID string) *discordgo.MessageSend {
+	minValues := 0
+	message := &discordgo.MessageSend{
+		Content: fmt.Sprintf("<@%s> I see you have posted a new message to the support page. What kind of alert is this? **Warning: this alert is live immediately!**", user),
+		Reference: &discordgo.MessageReference{
+			MessageID: messageID,
+			ChannelID: config().DiscordStatusChannelID,
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						CustomID:    CSPDiscordOptions,
+						Placeholder: "Options",
+						MinValues:   &minValues,
+						MaxValues:   2,
+						Options: []discordgo.SelectMenuOption{
+							{
+								Label: "Pin this message to the status page",
+								Value: CSPPin,
+							},
+							{
+								Label: fmt.Sprintf("Forward message to the #%s channel", channelName),
+								Value: CSPForward,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// One button per severity level, worst first, if they fit. Otherwise
+	// they go in a select menu.
+	levels := severityLevelsWorstFirst()
+	closeButton := discordgo.Button{
+		Label:    "❌Close",
+		Style:    discordgo.SecondaryButton,
+		CustomID: CSPCancel,
+	}
+	if len(levels)+1 > discordMaxButtonRows*5 {
+		if len(levels) > discordMaxSelectOptions {
+			log.Printf("Too many severity levels for Discord, only offering the worst %d\n", discordMaxSelectOptions)
+			levels = levels[:discordMaxSelectOptions]
+		}
+		var options []discordgo.SelectMenuOption
+		for _, level := range levels {
+			options = append(options, discordgo.SelectMenuOption{
+				Label: level.Label,
+				Value: CSPSetSeverityPrefix + level.Name,
+			})
+		}
+		message.Components = append(message.Components,
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{discordgo.SelectMenu{
+				CustomID:    CSPDiscordSeverity,
+				Placeholder: "What kind of alert is this?",
+				Options:     options,
+			}}},
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{closeButton}},
+		)
+		return message
+	}
+
+	var buttons []discordgo.MessageComponent
+	for _, level := range levels {
+		style := discordgo.SuccessButton
+		switch level.Impact {
+		case SeverityWarn:
+			style = discordgo.PrimaryButton
+		case SeverityError:
+			style = discordgo.DangerButton
+		}
+		buttons = append(buttons, discordgo.Button{
+			Label:    level.DiscordButtonLabel(),
+			Style:    style,
+			CustomID: CSPSetSeverityPrefix + level.Name,
+		})
+	}
+	buttons = append(buttons, closeButton)
+	for len(buttons) > 0 {
+		row := buttons[:min(len(buttons), 5)]
+		message.Components = append(message.Components, discordgo.ActionsRow{Components: row})
+		buttons = buttons[len(row):]
+	}
+	return message
+}
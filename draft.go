@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Drafts stay off the page until somebody publishes them, so that whoever
+// wrote them can check the preview first
+type Draft struct {
+	Author      string    `json:"author"`
+	CreatedAt   time.Time `json:"created_at"`
+	PublishedBy string    `json:"published_by,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+func (draft Draft) pending() bool {
+	return draft.PublishedBy == ""
+}
+
+// Where an update's preview can be seen, if we know where we're hosted
+func previewURL(token string) string {
+	c := config()
+	if c.PublicURL == "" || token == "" {
+		return ""
+	}
+	return strings.TrimSuffix(c.PublicURL, "/") + "/preview/" + token
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Pinned updates can unpin themselves after a while, so stale ones don't sit
+// on the page waiting for someone to notice.
+
+// Someone picked when an update should come down. A zero time means never.
+type PinExpiry struct {
+	At time.Time `json:"at"`
+}
+
+// Choices for when to unpin, as offered in the prompt and modal
+const (
+	ExpiryDefault = "default"
+	ExpiryNever   = "never"
+)
+
+var expiryChoices = []struct {
+	Value string
+	Label string
+}{
+	{ExpiryDefault, "Default for its severity"},
+	{ExpiryNever, "Never"},
+	{"1h", "In an hour"},
+	{"4h", "In 4 hours"},
+	{"24h", "In a day"},
+	{"72h", "In 3 days"},
+	{"168h", "In a week"},
+}
+
+// Sets or clears the expiry someone picked for an update
+func choosePinExpiry(updateID string, choice string, now time.Time) error {
+	switch choice {
+	case "", ExpiryDefault:
+		return store.clearPinExpiry(updateID)
+	case ExpiryNever:
+		return store.setPinExpiry(updateID, PinExpiry{})
+	}
+	d, err := time.ParseDuration(choice)
+	if err != nil {
+		return err
+	}
+	return store.setPinExpiry(updateID, PinExpiry{At: now.Add(d)})
+}
+
+// When a pinned update should come down, if ever. Whatever someone picked
+// wins. Otherwise it's the default for its severity after it was pinned, or
+// however long after it was marked OK, whichever comes first.
+func pinExpiry(update StatusUpdate) (at time.Time, ok bool) {
+	c := config()
+	if expiry, ok := store.pinExpiry(update.ID); ok {
+		return expiry.At, !expiry.At.IsZero()
+	}
+	if d := c.PinExpiry[update.Severity]; d > 0 {
+		if since, ok := store.pinnedSince(update.ID); ok {
+			at = since.Add(d)
+		}
+	}
+	if severityImpact(update.Severity) == SeverityOK && c.UnpinAfterOK > 0 {
+		if since, ok := store.markedOK(update.ID); ok {
+			afterOK := since.Add(c.UnpinAfterOK)
+			if at.IsZero() || afterOK.Before(at) {
+				at = afterOK
+			}
+		}
+	}
+	return at, !at.IsZero()
+}
+
+// Unpins anything that's expired. The cron job calls this every minute.
+func expirePins(csp CSPService, now time.Time) {
+	pinnedUpdates, _ := csp.Page().snapshot()
+	for _, update := range pinnedUpdates {
+		if update.ID == "" {
+			continue
+		}
+
+		// Nobody tells us when something was pinned or marked OK, so keep
+		// track. An old message pinned today counts from today.
+		err := store.recordPinned(update.ID, true, now)
+		if err != nil {
+			log.Println(err)
+		}
+		err = store.recordMarkedOK(update.ID, severityImpact(update.Severity) == SeverityOK, now)
+		if err != nil {
+			log.Println(err)
+		}
+
+		at, ok := pinExpiry(update)
+		if !ok || now.Before(at) {
+			continue
+		}
+		log.Printf("Unpinning %s, it expired at %s\n", update.ID, timeToHumanTime(at))
+		note := fmt.Sprintf("This update expired at %s, so I've taken it off the status page. Pin it again if it's still relevant.", timeToHumanTime(at))
+		err = csp.Unpin(update.ID, note)
+		if err != nil {
+			log.Printf("Could not unpin %s: %s\n", update.ID, err)
+			continue
+		}
+
+		// If someone pins it again, they mean it
+		err = store.setPinExpiry(update.ID, PinExpiry{})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
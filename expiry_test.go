@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Just enough of a backend to see what gets unpinned or announced
+type fakeService struct {
+	page        *CSPPage
+	unpinned    []string
+	announceErr error
+}
+
+func (f *fakeService) BuildStatusPage() error        { return nil }
+func (f *fakeService) StatusPage(c *gin.Context)     {}
+func (f *fakeService) Page() *CSPPage                { return f.page }
+func (f *fakeService) SendReminders(now bool) error  { return nil }
+func (f *fakeService) Announce(message string) error { return f.announceErr }
+func (f *fakeService) Run()                          {}
+func (f *fakeService) Unpin(updateID string, note string) error {
+	f.unpinned = append(f.unpinned, updateID)
+	return nil
+}
+
+func TestExpirePins(t *testing.T) {
+	defer func(s *CSPStore) { store = s }(store)
+	store = &CSPStore{}
+	withConfig(t, func(c *Config) {
+		c.PinExpiry = parseSeverityDurations("CSP_PIN_EXPIRY", "ok=24h, warn=72h", false)
+		c.UnpinAfterOK = time.Hour
+	})
+
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	update := func(id string, severity string, age time.Duration) (u StatusUpdate) {
+		u.ID = id
+		u.Time = now.Add(-age)
+		u.setSeverity(severity)
+		return u
+	}
+	stale := update("stale", SeverityWarn, 80*time.Hour)
+	fresh := update("fresh", SeverityWarn, time.Hour)
+	// Posted days ago, but only just pinned
+	repinned := update("repinned", SeverityWarn, 100*time.Hour)
+	critical := update("critical", SeverityError, 200*time.Hour)
+	picked := update("picked", SeverityWarn, 80*time.Hour)
+	resolved := update("resolved", SeverityOK, 2*time.Hour)
+	for _, pinned := range []StatusUpdate{stale, fresh, critical, picked, resolved} {
+		store.recordPinned(pinned.ID, true, pinned.Time)
+	}
+	store.setPinExpiry(picked.ID, PinExpiry{})
+	store.recordMarkedOK(resolved.ID, true, now.Add(-90*time.Minute))
+
+	service := &fakeService{page: &CSPPage{}}
+	service.page.setUpdates([]StatusUpdate{stale, fresh, repinned, critical, picked, resolved}, nil)
+	expirePins(service, now)
+
+	expected := []string{"stale", "resolved"}
+	if len(service.unpinned) != len(expected) {
+		t.Fatalf("Expected %v to be unpinned, got %v", expected, service.unpinned)
+	}
+	for i, id := range expected {
+		if service.unpinned[i] != id {
+			t.Errorf("Expected %v to be unpinned, got %v", expected, service.unpinned)
+		}
+	}
+
+	if at, ok := pinExpiry(repinned); !ok || !at.Equal(now.Add(72*time.Hour)) {
+		t.Errorf("Expected the default expiry to count from when it was pinned, got %s", at)
+	}
+
+	// Pinning it again by hand should stick
+	if _, ok := pinExpiry(stale); ok {
+		t.Error("Expected an update pinned again after expiring to stay pinned")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How often we go back to the file to see if anything changed
+const fileSourcePollInterval = time.Minute
+
+// A status update as written in a file source
+type fileUpdate struct {
+	Message    string    `json:"message"`
+	SentBy     string    `json:"sent_by"`
+	Time       time.Time `json:"time"`
+	Severity   string    `json:"severity"`
+	Components []string  `json:"components"`
+	Pinned     bool      `json:"pinned"`
+}
+
+type fileSourceContents struct {
+	Label   string       `json:"label"`
+	Updates []fileUpdate `json:"updates"`
+}
+
+// A read-only backend that takes its updates from a JSON file, for feeding in
+// updates from places that don't have a chat bot of their own.
+type CSPFile struct {
+	path     string
+	contents fileSourceContents
+	modTime  time.Time
+
+	page *CSPPage
+}
+
+func NewCSPFile(path string) (app CSPFile, err error) {
+	app.page = &CSPPage{}
+	app.path = path
+	err = app.readFile()
+	if err != nil {
+		return app, err
+	}
+
+	err = app.BuildStatusPage()
+	if err != nil {
+		return app, err
+	}
+	return app, nil
+}
+
+// Nuke the old slices and re-build them
+func (app *CSPFile) BuildStatusPage() (err error) {
+	log.Println("Building Status Page...")
+	updates := make([]StatusUpdate, 0)
+	pinnedUpdates := make([]StatusUpdate, 0)
+	for _, entry := range app.contents.Updates {
+		if strings.TrimSpace(entry.Message) == "" {
+			continue
+		}
+
+		var update StatusUpdate
+		update.HTML = MarkdownToHTML(entry.Message)
+		update.SentBy = entry.SentBy
+		update.tagComponents(entry.Components...)
+		update.tagComponents(componentsFromHashtags(entry.Message)...)
+		update.Origin = app.contents.Label
+		update.Time = entry.Time
+		update.setSeverity(entry.Severity)
+
+		if entry.Pinned {
+			pinnedUpdates = append(pinnedUpdates, update)
+		} else {
+			updates = append(updates, update)
+		}
+	}
+
+	recordUnpinned(app.page.setUpdates(pinnedUpdates, updates), time.Now())
+	return nil
+}
+
+// Pass-Thru the interface to the Page object
+func (app *CSPFile) StatusPage(gin *gin.Context) {
+	app.page.statusPage(gin)
+}
+
+func (app *CSPFile) Page() *CSPPage {
+	return app.page
+}
+
+// There's nobody to remind about a file.
+func (app *CSPFile) SendReminders(now bool) error {
+	return nil
+}
+
+// Nor anyone to tell about anything.
+func (app *CSPFile) Announce(message string) error {
+	return nil
+}
+
+// Pins live in the file, so whoever edits it has to take them down
+func (app *CSPFile) Unpin(updateID string, note string) error {
+	return errors.New("can't unpin updates in a file")
+}
+
+func (app *CSPFile) Run() {
+	for range time.Tick(fileSourcePollInterval) {
+		info, err := os.Stat(app.path)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if !info.ModTime().After(app.modTime) {
+			continue
+		}
+
+		err = app.readFile()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		err = app.BuildStatusPage()
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (app *CSPFile) readFile() error {
+	log.Println("Reading updates from: ", app.path)
+	info, err := os.Stat(app.path)
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(app.path)
+	if err != nil {
+		return err
+	}
+
+	var contents fileSourceContents
+	err = json.Unmarshal(raw, &contents)
+	if err != nil {
+		return err
+	}
+	if contents.Label == "" {
+		contents.Label = strings.TrimSuffix(filepath.Base(app.path), filepath.Ext(app.path))
+	}
+
+	app.contents = contents
+	app.modTime = info.ModTime()
+	return nil
+}
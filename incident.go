@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Where we're at with an incident. Severity says how bad things are, this says
+// whether we're still looking into it or just keeping an eye on it.
+const (
+	StateInvestigating = "investigating"
+	StateIdentified    = "identified"
+	StateMonitoring    = "monitoring"
+	StateResolved      = "resolved"
+)
+
+// In the order an incident usually goes through them
+var incidentStates = []string{
+	StateInvestigating,
+	StateIdentified,
+	StateMonitoring,
+	StateResolved,
+}
+
+type StateChange struct {
+	Time  time.Time `json:"time"`
+	State string    `json:"state"`
+}
+
+func incidentStateLabel(state string) string {
+	switch state {
+	case StateInvestigating:
+		return "Investigating"
+	case StateIdentified:
+		return "Identified"
+	case StateMonitoring:
+		return "Monitoring"
+	case StateResolved:
+		return "Resolved"
+	case MaintenanceScheduled:
+		return "Scheduled"
+	case MaintenanceInProgress:
+		return "Maintenance in progress"
+	case MaintenanceCompleted:
+		return "Completed"
+	}
+	return ""
+}
+
+// Follow-ups can set the state by starting with it, e.g. "Monitoring: the
+// fix is out". We only look at the first word so that "still investigating"
+// halfway through a sentence doesn't count.
+func parseIncidentState(message string) (state string, ok bool) {
+	fields := strings.Fields(message)
+	if len(fields) == 0 {
+		return "", false
+	}
+	word := strings.ToLower(strings.Trim(fields[0], ":.,!-*_"))
+	if stringInSlice(incidentStates, word) {
+		return word, true
+	}
+	return "", false
+}
+
+func (update *StatusUpdate) setIncidentStates(history []StateChange) {
+	update.StateHistory = history
+	if len(history) > 0 {
+		update.State = history[len(history)-1].State
+	}
+}
+
+func (update StatusUpdate) StateLabel() string {
+	return incidentStateLabel(update.State)
+}
+
+func (update StatusUpdate) StateBadgeClass() string {
+	return stateBadgeClass(update.State)
+}
+
+func (change StateChange) Label() string {
+	return incidentStateLabel(change.State)
+}
+
+func (change StateChange) TimeStamp() string {
+	return timeToHumanTime(change.Time)
+}
+
+func (change StateChange) ISOTime() string {
+	return isoTime(change.Time)
+}
+
+func (change StateChange) BadgeClass() string {
+	return stateBadgeClass(change.State)
+}
+
+func stateBadgeClass(state string) string {
+	switch state {
+	case StateInvestigating:
+		return "text-bg-danger"
+	case StateIdentified:
+		return "text-bg-warning"
+	case StateMonitoring:
+		return "text-bg-info"
+	case StateResolved, MaintenanceCompleted:
+		return "text-bg-success"
+	case MaintenanceInProgress:
+		return "text-bg-primary"
+	}
+	return "text-bg-secondary"
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseIncidentState(t *testing.T) {
+	replies := map[string]string{
+		" Monitoring: the fix is out":            StateMonitoring,
+		"*Identified* - bad optic on the link":   StateIdentified,
+		"resolved!":                              StateResolved,
+		"We are still investigating":             "",
+		"":                                       "",
+		"Investigatinggg, give us a few minutes": "",
+	}
+	for reply, expected := range replies {
+		state, ok := parseIncidentState(reply)
+		if state != expected || ok != (expected != "") {
+			t.Errorf("State for '%s' did not match.\nExpected: '%s'\nReceived: '%s'", reply, expected, state)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"log"
 	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -15,31 +17,72 @@ type Config struct {
 	LogoURL    string
 	FaviconURL string
 
+	NavLinks       []Link
+	FooterSections []FooterSection
+
+	Sources []string
+
+	SlackLabel            string
 	SlackTeamID           string
 	SlackAccessToken      string
 	SlackAppToken         string
-	SlackStatusChannelID  string
+	SlackStatusChannels   string
 	SlackForwardChannelID string
-	SlackBotID            string
 	SlackTruncation       string
+	SlackPublishers       string
+	SlackPublisherGroups  string
+	SlackEscalationGroup  string
+
+	DiscordLabel            string
+	DiscordToken            string
+	DiscordStatusChannelID  string
+	DiscordForwardChannelID string
+	DiscordTruncation       string
+
+	MattermostLabel           string
+	MattermostURL             string
+	MattermostToken           string
+	MattermostStatusChannelID string
+	MattermostTruncation      string
+
+	SeverityLevels []SeverityLevel
+	PinEmoji       string
+
+	ApprovalMode string
+	Drafts       bool
+
+	PinExpiry    map[string]time.Duration
+	UnpinAfterOK time.Duration
+
+	PublicURL string
+	APIToken  string
 
-	StatusNeutralColor string
-	StatusOKColor      string
-	StatusOKEmoji      string
-	StatusWarnColor    string
-	StatusWarnEmoji    string
-	StatusErrorColor   string
-	StatusErrorEmoji   string
+	Location *time.Location
+
+	RecurringMaintenance []RecurringMaintenance
+	MaintenanceReminder  time.Duration
+
+	Components []Component
+
+	StateFile string
 
 	NominalMessage string
 	NominalSentBy  string
 	HelpMessage    string
 
 	ReminderSchedule string
+	ReminderMode     string
+	ReminderAfter    map[string]time.Duration
+	EscalateAfter    map[string]time.Duration
 }
 
-// Useful global variables
-var config Config
+// Useful global variables. The config gets swapped out whole when it's
+// reloaded, so hang on to what config() gives you if you need it to add up.
+var liveConfig atomic.Pointer[Config]
+
+func config() *Config {
+	return liveConfig.Load()
+}
 
 func init() {
 	// Load environment variables one way or another
@@ -48,47 +91,152 @@ func init() {
 		log.Println("Couldn't load .env file")
 	}
 
-	config.OrgName = os.Getenv("CSP_ORG_NAME")
-	config.LogoURL = os.Getenv("CSP_LOGO_URL")
-	config.FaviconURL = os.Getenv("CSP_FAVICON_URL")
-
-	config.SlackTeamID = os.Getenv("CSP_SLACK_TEAMID")
-	config.SlackAccessToken = os.Getenv("CSP_SLACK_ACCESS_TOKEN")
-	config.SlackAppToken = os.Getenv("CSP_SLACK_APP_TOKEN")
-	config.SlackStatusChannelID = os.Getenv("CSP_SLACK_STATUS_CHANNEL")
-	config.SlackForwardChannelID = os.Getenv("CSP_SLACK_FORWARD_CHANNEL")
-	config.SlackTruncation = os.Getenv("CSP_SLACK_TRUNCATION")
-
-	config.StatusNeutralColor = os.Getenv("CSP_CARD_NEUTRAL_COLOR")
-	config.StatusOKColor = os.Getenv("CSP_CARD_OK_COLOR")
-	config.StatusOKEmoji = os.Getenv("CSP_CARD_OK_EMOJI")
-	config.StatusWarnColor = os.Getenv("CSP_CARD_WARN_COLOR")
-	config.StatusWarnEmoji = os.Getenv("CSP_CARD_WARN_EMOJI")
-	config.StatusErrorColor = os.Getenv("CSP_CARD_ERROR_COLOR")
-	config.StatusErrorEmoji = os.Getenv("CSP_CARD_ERROR_EMOJI")
-
-	config.NominalMessage = os.Getenv("CSP_NOMINAL_MESSAGE")
-	config.NominalSentBy = os.Getenv("CSP_NOMINAL_SENT_BY")
-	config.HelpMessage = os.Getenv("CSP_HELP_LINK")
-
-	config.ReminderSchedule = os.Getenv("CSP_REMINDER_SCHEDULE")
+	c, settings, err := loadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// main checks it before going any further
+	fileSettings = settings
+	liveConfig.Store(&c)
+}
+
+// Reads the config file, if there is one, then the environment over the top
+// of it. Hands back the file's settings too, for whoever swaps the config in.
+func loadConfig() (c Config, settings configSettings, err error) {
+	file, err := loadConfigFile(os.Getenv("CSP_CONFIG_FILE"))
+	if err != nil {
+		return c, nil, err
+	}
+	settings = file.Settings
+	c.NavLinks = file.NavLinks
+	c.FooterSections = file.FooterSections
+
+	c.OrgName = settings.get("CSP_ORG_NAME")
+	c.LogoURL = settings.get("CSP_LOGO_URL")
+	c.FaviconURL = settings.get("CSP_FAVICON_URL")
+
+	c.Sources = splitList(settings.get("CSP_SOURCES"))
+
+	c.SlackLabel = settings.get("CSP_SLACK_LABEL")
+	c.SlackTeamID = settings.get("CSP_SLACK_TEAMID")
+	c.SlackAccessToken = settings.get("CSP_SLACK_ACCESS_TOKEN")
+	c.SlackAppToken = settings.get("CSP_SLACK_APP_TOKEN")
+	c.SlackStatusChannels = settings.get("CSP_SLACK_STATUS_CHANNEL")
+	c.SlackForwardChannelID = settings.get("CSP_SLACK_FORWARD_CHANNEL")
+	c.SlackTruncation = settings.get("CSP_SLACK_TRUNCATION")
+	c.SlackPublishers = settings.get("CSP_SLACK_PUBLISHERS")
+	c.SlackPublisherGroups = settings.get("CSP_SLACK_PUBLISHER_GROUPS")
+	c.SlackEscalationGroup = settings.get("CSP_SLACK_ESCALATION_GROUP")
+
+	c.DiscordLabel = settings.get("CSP_DISCORD_LABEL")
+	c.DiscordToken = settings.get("CSP_DISCORD_TOKEN")
+	c.DiscordStatusChannelID = settings.get("CSP_DISCORD_STATUS_CHANNEL")
+	c.DiscordForwardChannelID = settings.get("CSP_DISCORD_FORWARD_CHANNEL")
+	c.DiscordTruncation = settings.get("CSP_DISCORD_TRUNCATION")
+
+	c.MattermostLabel = settings.get("CSP_MATTERMOST_LABEL")
+	c.MattermostURL = settings.get("CSP_MATTERMOST_URL")
+	c.MattermostToken = settings.get("CSP_MATTERMOST_TOKEN")
+	c.MattermostStatusChannelID = settings.get("CSP_MATTERMOST_STATUS_CHANNEL")
+	c.MattermostTruncation = settings.get("CSP_MATTERMOST_TRUNCATION")
+
+	c.SeverityLevels = parseSeverityLevels(settings.get("CSP_SEVERITY_LEVELS"))
+	if len(c.SeverityLevels) == 0 {
+		c.SeverityLevels = defaultSeverityLevels(settings)
+	}
+	c.PinEmoji = settings.getDefault("CSP_PIN_EMOJI", "pushpin")
+
+	c.ApprovalMode = parseApprovalMode(settings.get("CSP_APPROVAL_MODE"))
+	c.Drafts = settings.get("CSP_DRAFTS") == "true"
+
+	// A severity that isn't listed never expires
+	c.PinExpiry = parseSeverityDurations("CSP_PIN_EXPIRY", settings.get("CSP_PIN_EXPIRY"), false)
+	if unpinAfterOK := settings.get("CSP_UNPIN_AFTER_OK"); unpinAfterOK != "" {
+		c.UnpinAfterOK, err = time.ParseDuration(unpinAfterOK)
+		if err != nil {
+			log.Printf("Bad CSP_UNPIN_AFTER_OK, not unpinning OK updates. %s\n", err)
+		}
+	}
+
+	c.PublicURL = settings.get("CSP_PUBLIC_URL")
+	c.APIToken = settings.get("CSP_API_TOKEN")
+
+	c.Location = loadLocation(settings.getDefault("CSP_TIMEZONE", "America/New_York"))
+
+	c.RecurringMaintenance = parseRecurringMaintenance(settings.get("CSP_RECURRING_MAINTENANCE"))
+	c.MaintenanceReminder, err = time.ParseDuration(settings.getDefault("CSP_MAINTENANCE_REMINDER", "24h"))
+	if err != nil {
+		log.Printf("Bad CSP_MAINTENANCE_REMINDER, not sending maintenance reminders. %s\n", err)
+	}
+
+	c.Components = parseComponents(settings.get("CSP_COMPONENTS"))
+
+	c.StateFile = settings.get("CSP_STATE_FILE")
+
+	c.NominalMessage = settings.get("CSP_NOMINAL_MESSAGE")
+	c.NominalSentBy = settings.get("CSP_NOMINAL_SENT_BY")
+	c.HelpMessage = settings.get("CSP_HELP_LINK")
+
+	c.ReminderSchedule = settings.get("CSP_REMINDER_SCHEDULE")
+	c.ReminderMode = parseReminderMode(settings.get("CSP_REMINDER_MODE"))
+	c.ReminderAfter = parseSeverityDurations("CSP_REMINDER_AFTER", settings.getDefault("CSP_REMINDER_AFTER", "24h"), true)
+	c.EscalateAfter = parseSeverityDurations("CSP_ESCALATE_AFTER", settings.getDefault("CSP_ESCALATE_AFTER", "72h"), true)
+	return c, settings, nil
 }
 
 func main() {
 	useSlack := flag.Bool("slack", true, "Launch an instance of CSP to connect to Slack")
-	pinReminders := flag.Bool("send-reminders", false, "Check for pinned items and send a reminder if it's been longer than a day.")
+	useDiscord := flag.Bool("discord", false, "Launch an instance of CSP to connect to Discord instead of Slack")
+	useMattermost := flag.Bool("mattermost", false, "Launch an instance of CSP to connect to Mattermost instead of Slack")
+	pinReminders := flag.Bool("send-reminders", false, "Check for pinned items and send a reminder once they've been up long enough.")
 	sendRemindersNow := flag.Bool("remind-now", false, "Send reminders right away.")
 	flag.Parse()
 
-	var csp CSPService
+	conf := config()
+	var err error
+	store, err = LoadStore(conf.StateFile)
+	if err != nil {
+		log.Fatalf("Could not load state from %s. %s", conf.StateFile, err)
+	}
 
-	if *useSlack {
-		log.Println("Connecting to Slack...")
-		cspSlack, err := NewCSPSlack()
-		csp = &cspSlack
+	// CSP_SOURCES takes precedence over the flags, so that we can pull from
+	// more than one place at once.
+	sources := conf.Sources
+	if len(sources) == 0 {
+		if *useDiscord {
+			sources = []string{"discord"}
+		} else if *useMattermost {
+			sources = []string{"mattermost"}
+		} else if *useSlack {
+			sources = []string{"slack"}
+		}
+	}
+
+	if problems := validateConfig(*conf, fileSettings, sources, *pinReminders); len(problems) > 0 {
+		for _, problem := range problems {
+			log.Println(problem)
+		}
+		log.Fatalf("Found %d problem(s) with the configuration. Fix them and start again.", len(problems))
+	}
+
+	var services []CSPService
+	for _, source := range sources {
+		service, err := NewCSPService(source)
 		if err != nil {
-			log.Fatalf("Could not set up new CSPSlack service. %s", err)
+			log.Fatalf("Could not set up source %s. %s", source, err)
 		}
+		services = append(services, service)
+	}
+
+	var csp CSPService
+	switch len(services) {
+	case 0:
+		log.Fatal("No sources configured.")
+	case 1:
+		csp = services[0]
+	default:
+		cspAggregate := NewCSPAggregate(services)
+		csp = &cspAggregate
 	}
 
 	if *sendRemindersNow {
@@ -99,25 +247,56 @@ func main() {
 		os.Exit(0)
 	}
 
+	c := cron.New()
+	watcher := configWatcher{configPath: os.Getenv("CSP_CONFIG_FILE"), sources: sources}
 	if *pinReminders {
-		log.Printf("Setting up reminders. Schedule is %s\n", config.ReminderSchedule)
-		c := cron.New()
-		c.AddFunc(config.ReminderSchedule, func() {
-			err := csp.SendReminders(false)
-			if err != nil {
-				log.Printf("Cronjob returned error: %s\n", err)
-			}
-		})
-		c.Start()
+		log.Printf("Setting up reminders. Schedule is %s\n", conf.ReminderSchedule)
+		watcher.reminders = &reminderJob{cron: c, csp: csp}
+		err = watcher.reminders.reschedule(conf.ReminderSchedule)
+		if err != nil {
+			log.Fatalf("Could not schedule reminders. %s", err)
+		}
 	}
+	// Starts and finishes scheduled maintenance on time
+	tickMaintenance(csp, time.Now())
+	c.AddFunc("@every 1m", func() {
+		tickMaintenance(csp, time.Now())
+	})
+	// Takes down pins that have overstayed their welcome
+	c.AddFunc("@every 1m", func() {
+		expirePins(csp, time.Now())
+	})
+	c.Start()
 
 	go csp.Run()
 
+	// Components can turn up in a reload, so this runs either way
+	go watchComponentHistory(csp)
+
+	// The templates and the config file get picked up again when they change
+	watcher.html = &reloadableHTML{pattern: "templates/*"}
+	err = watcher.html.load()
+	if err != nil {
+		log.Fatalf("Could not load templates. %s", err)
+	}
+	go watcher.run()
+
 	web := gin.Default()
-	web.LoadHTMLGlob("templates/*")
+	web.HTMLRender = watcher.html
 	web.Static("/static", "./static")
 
 	web.GET("/", csp.StatusPage)
+	web.GET("/api/status", func(c *gin.Context) {
+		csp.Page().statusAPI(c)
+	})
+	web.GET("/preview/:token", func(c *gin.Context) {
+		csp.Page().previewPage(c)
+	})
+	web.POST("/api/maintenance", createMaintenanceAPI)
+	web.DELETE("/api/maintenance/:id", cancelMaintenanceAPI)
+	web.GET("/calendar.ics", func(c *gin.Context) {
+		csp.Page().calendar(c)
+	})
 	web.GET("/health", health)
 
 	_ = web.Run()
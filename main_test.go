@@ -0,0 +1,13 @@
+package main
+
+import "testing"
+
+// Swaps in a copy of the config with some changes for the length of a test,
+// so nothing leaks into the tests that run after it
+func withConfig(t *testing.T, change func(c *Config)) {
+	previous := config()
+	next := *previous
+	change(&next)
+	liveConfig.Store(&next)
+	t.Cleanup(func() { liveConfig.Store(previous) })
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Where a maintenance window is at. The cron job moves them along on its
+// own once they're scheduled.
+const (
+	MaintenanceScheduled  = "scheduled"
+	MaintenanceInProgress = "in_progress"
+	MaintenanceCompleted  = "completed"
+	MaintenanceCancelled  = "cancelled"
+)
+
+// Finished maintenance stays in the history for a while, then gets dropped.
+// Recurring maintenance shows up on the page this far ahead of time.
+const (
+	maintenanceHistory = 14 * 24 * time.Hour
+	maintenanceKeep    = 90 * 24 * time.Hour
+	recurringLookahead = 30 * 24 * time.Hour
+)
+
+// What can be wrong with a new maintenance window, so the form can point at
+// the field that needs fixing
+var (
+	errMaintenanceTitle     = errors.New("maintenance needs a title")
+	errMaintenanceStart     = errors.New("maintenance needs a start")
+	errMaintenanceEnd       = errors.New("maintenance needs an end")
+	errMaintenanceBackwards = errors.New("maintenance has to end after it starts")
+	errMaintenanceOver      = errors.New("maintenance can't be over already")
+	errMaintenanceComponent = errors.New("unknown component")
+)
+
+// Planned work we know about ahead of time
+type Maintenance struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Components  []string  `json:"components,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Status      string    `json:"status"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	Reminded    bool      `json:"reminded,omitempty"`
+}
+
+// Maintenance that happens on a rota. Each occurrence gets scheduled like any
+// other maintenance once it's close enough.
+type RecurringMaintenance struct {
+	Title       string
+	Description string
+	Components  []string
+	Recurrence  Recurrence
+	Duration    time.Duration
+}
+
+// Recurring maintenance is configured one window per line, as
+// "Title|Rule|Duration|Components|Description". The components and
+// description are optional, e.g.
+//
+//	Rooftop work|DTSTART;TZID=America/New_York:20240312T020000 RRULE:FREQ=MONTHLY;BYDAY=2TU|2h|Backbone,Hubs
+func parseRecurringMaintenance(value string) []RecurringMaintenance {
+	windows, problems := readRecurringMaintenance(value)
+	for _, problem := range problems {
+		log.Printf("%s. Skipping it.\n", problem)
+	}
+	return windows
+}
+
+// Does the work for parseRecurringMaintenance, and says what was wrong with
+// any lines it had to skip
+func readRecurringMaintenance(value string) (windows []RecurringMaintenance, problems []string) {
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.SplitN(line, "|", 5)
+		if len(fields) < 3 {
+			problems = append(problems, fmt.Sprintf("Recurring maintenance '%s' needs a title, a rule and a duration", line))
+			continue
+		}
+		window := RecurringMaintenance{Title: strings.TrimSpace(fields[0])}
+		var err error
+		window.Recurrence, err = parseRecurrence(fields[1])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("Could not parse the rule for recurring maintenance '%s': %s", window.Title, err))
+			continue
+		}
+		window.Duration, err = time.ParseDuration(strings.TrimSpace(fields[2]))
+		if err != nil || window.Duration <= 0 {
+			problems = append(problems, fmt.Sprintf("Recurring maintenance '%s' has a bad duration '%s'", window.Title, strings.TrimSpace(fields[2])))
+			continue
+		}
+		if len(fields) > 3 {
+			window.Components = splitList(fields[3])
+		}
+		if len(fields) > 4 {
+			window.Description = strings.TrimSpace(fields[4])
+		}
+		windows = append(windows, window)
+	}
+	return windows, problems
+}
+
+// The next occurrence that isn't over yet, if it's coming up soon. Its ID is
+// derived from the window and its start, so it only ever gets made once, and
+// cancelling it sticks.
+func (window RecurringMaintenance) nextOccurrence(now time.Time) (maintenance Maintenance, ok bool) {
+	start, ok := window.Recurrence.next(now.Add(-window.Duration))
+	if !ok || start.Sub(now) > recurringLookahead {
+		return maintenance, false
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(window.Title))
+	return Maintenance{
+		ID:          fmt.Sprintf("recurring-%08x-%d", hash.Sum32(), start.Unix()),
+		Title:       window.Title,
+		Description: window.Description,
+		Components:  append([]string{}, window.Components...),
+		Start:       start,
+		End:         start.Add(window.Duration),
+		Status:      MaintenanceScheduled,
+		CreatedBy:   "Recurring maintenance",
+	}, true
+}
+
+// Puts the next occurrence of each recurring window on the page
+func scheduleRecurringMaintenance(now time.Time) {
+	for _, window := range config().RecurringMaintenance {
+		maintenance, ok := window.nextOccurrence(now)
+		if !ok {
+			continue
+		}
+		if _, exists := store.maintenance(maintenance.ID); exists {
+			continue
+		}
+		log.Printf("Scheduling '%s' for %s\n", maintenance.Title, maintenance.StartTimeStamp())
+		err := store.setMaintenance(maintenance)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// Lets the status channels know about maintenance that's coming up soon
+func remindMaintenance(csp CSPService, now time.Time) {
+	c := config()
+	if c.MaintenanceReminder <= 0 {
+		return
+	}
+	for _, maintenance := range store.maintenances() {
+		if maintenance.Status != MaintenanceScheduled || maintenance.Reminded || maintenance.Start.Sub(now) > c.MaintenanceReminder {
+			continue
+		}
+		message := fmt.Sprintf("🚧 Heads up: \"%s\" starts %s and should be done by %s.", maintenance.Title, maintenance.StartTimeStamp(), maintenance.EndTimeStamp())
+		if len(maintenance.Components) > 0 {
+			message += fmt.Sprintf(" Affects %s.", strings.Join(maintenance.Components, ", "))
+		}
+		err := csp.Announce(message)
+		if err != nil {
+			log.Printf("Could not send maintenance reminder: %s\n", err)
+			continue
+		}
+		maintenance.Reminded = true
+		err = store.setMaintenance(maintenance)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// Everything the cron job does for maintenance, every minute
+func tickMaintenance(csp CSPService, now time.Time) {
+	scheduleRecurringMaintenance(now)
+	advanceMaintenance(now)
+	remindMaintenance(csp, now)
+}
+
+// Checks a new maintenance window makes sense, and fills in whatever it can
+func (maintenance *Maintenance) validate(now time.Time) error {
+	maintenance.Title = strings.TrimSpace(maintenance.Title)
+	if maintenance.Title == "" {
+		return errMaintenanceTitle
+	}
+	if maintenance.Start.IsZero() {
+		return errMaintenanceStart
+	}
+	if maintenance.End.IsZero() {
+		return errMaintenanceEnd
+	}
+	if !maintenance.End.After(maintenance.Start) {
+		return errMaintenanceBackwards
+	}
+	if !maintenance.End.After(now) {
+		return errMaintenanceOver
+	}
+	for i, name := range maintenance.Components {
+		component, ok := findComponent(name)
+		if !ok {
+			return fmt.Errorf("%w '%s'", errMaintenanceComponent, name)
+		}
+		maintenance.Components[i] = component.Name
+	}
+	if maintenance.ID == "" {
+		id, err := randomToken()
+		if err != nil {
+			return err
+		}
+		maintenance.ID = id
+	}
+	maintenance.Status = MaintenanceScheduled
+	return nil
+}
+
+// Where a maintenance window should be at by now. Cancelled ones stay that
+// way.
+func (maintenance Maintenance) statusAt(now time.Time) string {
+	switch {
+	case maintenance.Status == MaintenanceCancelled:
+		return MaintenanceCancelled
+	case !now.Before(maintenance.End):
+		return MaintenanceCompleted
+	case !now.Before(maintenance.Start):
+		return MaintenanceInProgress
+	}
+	return MaintenanceScheduled
+}
+
+func (maintenance Maintenance) StartTimeStamp() string {
+	return timeToHumanTime(maintenance.Start)
+}
+
+func (maintenance Maintenance) EndTimeStamp() string {
+	return timeToHumanTime(maintenance.End)
+}
+
+func (maintenance Maintenance) DescriptionHTML() template.HTML {
+	return MrkdwnToHTML(maintenance.Description)
+}
+
+// How maintenance looks among the regular updates, once it's started
+func (maintenance Maintenance) statusUpdate() (update StatusUpdate) {
+	update.ID = "maintenance/" + maintenance.ID
+	update.HTML = template.HTML(fmt.Sprintf("<p><strong>%s</strong></p>", template.HTMLEscapeString(maintenance.Title)))
+	if maintenance.Description != "" {
+		update.HTML += maintenance.DescriptionHTML()
+	}
+	update.SentBy = maintenance.CreatedBy
+	update.Origin = "Scheduled maintenance"
+	update.tagComponents(maintenance.Components...)
+	if len(update.Components) > 0 {
+		update.Component = update.Components[0]
+	}
+	update.ExpectedResolution = maintenance.End
+	update.Time = maintenance.Start
+	history := []StateChange{{Time: maintenance.Start, State: MaintenanceInProgress}}
+	switch maintenance.Status {
+	case MaintenanceInProgress:
+		update.setSeverity(maintenanceSeverity())
+	case MaintenanceCompleted:
+		update.setSeverity(resolvedSeverity())
+		update.ExpectedResolution = time.Time{}
+		history = append(history, StateChange{Time: maintenance.End, State: MaintenanceCompleted})
+	}
+	update.setIncidentStates(history)
+	return update
+}
+
+// Maintenance that's underway gets its own level if there's one called
+// "maintenance", otherwise the mildest one that means trouble
+func maintenanceSeverity() string {
+	if _, ok := severityLevel("maintenance"); ok {
+		return "maintenance"
+	}
+	return severityWithImpact(SeverityWarn)
+}
+
+// Calls off maintenance that hasn't finished yet
+func cancelMaintenance(id string) (maintenance Maintenance, err error) {
+	maintenance, ok := store.maintenance(id)
+	if !ok {
+		return maintenance, errors.New("no such maintenance")
+	}
+	if maintenance.Status == MaintenanceCompleted {
+		return maintenance, errors.New("maintenance is already over")
+	}
+	maintenance.Status = MaintenanceCancelled
+	log.Printf("Maintenance '%s' was cancelled\n", maintenance.Title)
+	return maintenance, store.setMaintenance(maintenance)
+}
+
+// Moves maintenance windows along as they start and finish. The cron job
+// calls this every minute.
+func advanceMaintenance(now time.Time) {
+	for _, maintenance := range store.maintenances() {
+		status := maintenance.statusAt(now)
+		if status == maintenance.Status {
+			continue
+		}
+		log.Printf("Maintenance '%s' is now %s\n", maintenance.Title, status)
+		maintenance.Status = status
+		err := store.setMaintenance(maintenance)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	err := store.pruneMaintenance(now.Add(-maintenanceKeep))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// Maintenance that hasn't started yet, soonest first
+func upcomingMaintenance() (upcoming []Maintenance) {
+	for _, maintenance := range store.maintenances() {
+		if maintenance.Status == MaintenanceScheduled {
+			upcoming = append(upcoming, maintenance)
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].Start.Before(upcoming[j].Start)
+	})
+	return upcoming
+}
+
+// Adds maintenance that's underway to the pinned updates, and maintenance
+// that finished recently to the history
+func withMaintenance(pinnedUpdates []StatusUpdate, updates []StatusUpdate, now time.Time) ([]StatusUpdate, []StatusUpdate) {
+	var current, past []StatusUpdate
+	for _, maintenance := range store.maintenances() {
+		switch maintenance.Status {
+		case MaintenanceInProgress:
+			current = append(current, maintenance.statusUpdate())
+		case MaintenanceCompleted:
+			if now.Sub(maintenance.End) < maintenanceHistory {
+				past = append(past, maintenance.statusUpdate())
+			}
+		}
+	}
+	if len(current) > 0 {
+		pinnedUpdates = append(append([]StatusUpdate{}, pinnedUpdates...), current...)
+		sortNewestFirst(pinnedUpdates)
+	}
+	if len(past) > 0 {
+		updates = append(append([]StatusUpdate{}, updates...), past...)
+		sortNewestFirst(updates)
+	}
+	return pinnedUpdates, updates
+}
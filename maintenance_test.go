@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdvanceMaintenance(t *testing.T) {
+	defer func(s *CSPStore) { store = s }(store)
+	store = &CSPStore{}
+
+	start := time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC)
+	maintenance := Maintenance{
+		Title: "Replacing the router",
+		Start: start,
+		End:   start.Add(2 * time.Hour),
+	}
+	err := maintenance.validate(start.Add(-7 * 24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.setMaintenance(maintenance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		at       time.Time
+		status   string
+		pinned   int
+		upcoming int
+	}{
+		{start.Add(-time.Hour), MaintenanceScheduled, 0, 1},
+		{start, MaintenanceInProgress, 1, 0},
+		{start.Add(2 * time.Hour), MaintenanceCompleted, 0, 0},
+	}
+	for _, step := range steps {
+		advanceMaintenance(step.at)
+		current, _ := store.maintenance(maintenance.ID)
+		if current.Status != step.status {
+			t.Errorf("At %s expected %s, got %s", step.at, step.status, current.Status)
+		}
+		pinned, _ := withMaintenance(nil, nil, step.at)
+		if len(pinned) != step.pinned || len(upcomingMaintenance()) != step.upcoming {
+			t.Errorf("At %s expected %d pinned and %d upcoming, got %d and %d", step.at, step.pinned, step.upcoming, len(pinned), len(upcomingMaintenance()))
+		}
+	}
+}
+
+func TestValidateMaintenance(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	backwards := Maintenance{Title: "Oops", Start: now.Add(2 * time.Hour), End: now.Add(time.Hour)}
+	if backwards.validate(now) == nil {
+		t.Error("Maintenance that ends before it starts should not validate")
+	}
+	over := Maintenance{Title: "Too late", Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}
+	if over.validate(now) == nil {
+		t.Error("Maintenance that's already over should not validate")
+	}
+}
+
+func TestMaintenanceErrorBlock(t *testing.T) {
+	withConfig(t, func(c *Config) { c.Components = parseComponents("Core/Backbone") })
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for block, maintenance := range map[string]Maintenance{
+		CSPMaintenanceTitle: {Title: " ", Start: now, End: now.Add(time.Hour)},
+		CSPMaintenanceStart: {Title: "Upgrade", End: now.Add(time.Hour)},
+		CSPMaintenanceEnd:   {Title: "Upgrade", Start: now.Add(2 * time.Hour), End: now.Add(time.Hour)},
+		CSPComponentsBlock:  {Title: "Upgrade", Start: now, End: now.Add(time.Hour), Components: []string{"Website"}},
+	} {
+		err := maintenance.validate(now)
+		if err == nil {
+			t.Errorf("Expected %+v not to validate", maintenance)
+			continue
+		}
+		if got := maintenanceErrorBlock(err); got != block {
+			t.Errorf("Expected '%s' to go under %s, got %s", err, block, got)
+		}
+	}
+}
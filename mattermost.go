@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CSPMattermost struct {
+	client *mattermostClient
+
+	botID       string
+	botUsername string
+	teamName    string
+	label       string
+
+	channelHistory []*mattermostPost
+
+	// Prompts we've posted, keyed by the update they were posted for, so we
+	// can clean them up once somebody picks a severity.
+	prompts map[string]string
+
+	page *CSPPage
+}
+
+func NewCSPMattermost() (app CSPMattermost, err error) {
+	c := config()
+	app.page = &CSPPage{}
+	app.client = newMattermostClient(c.MattermostURL, c.MattermostToken)
+	app.prompts = make(map[string]string)
+
+	// Get some deets we'll need from the Mattermost API
+	me, err := app.client.getMe()
+	if err != nil {
+		return app, err
+	}
+	app.botID = me.ID
+	app.botUsername = me.Username
+
+	channel, err := app.client.getChannel(c.MattermostStatusChannelID)
+	if err != nil {
+		return app, err
+	}
+	team, err := app.client.getTeam(channel.TeamID)
+	if err != nil {
+		return app, err
+	}
+	app.teamName = team.Name
+	app.label = c.MattermostLabel
+	if app.label == "" {
+		app.label = team.DisplayName
+	}
+
+	// Get the channel history
+	err = app.getChannelHistory()
+	if err != nil {
+		return app, err
+	}
+
+	// Initialize the actual data we need for the status page
+	err = app.BuildStatusPage()
+	if err != nil {
+		return app, err
+	}
+	return app, nil
+}
+
+// Nuke the old slices and re-build them
+func (app *CSPMattermost) BuildStatusPage() (err error) {
+	log.Println("Building Status Page...")
+	updates := make([]StatusUpdate, 0)
+	pinnedUpdates := make([]StatusUpdate, 0)
+	for _, post := range app.channelHistory {
+		// Ignore posts that don't mention us, and replies to them. Also,
+		// ignore posts that mention us but are empty!
+		if post.RootID != "" || !app.botActionablyMentioned(post.Message) {
+			continue
+		}
+
+		author, err := app.client.getUser(post.UserID)
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+
+		var update StatusUpdate
+		update.HTML = MarkdownToHTML(app.channelLinksToMarkdown(app.stripBotMention(post.Message)))
+		update.ID = post.ID
+		update.SentBy = mattermostDisplayName(author)
+		update.tagComponents(componentsFromHashtags(post.Message)...)
+		update.Origin = app.label
+		update.Time = time.UnixMilli(post.CreateAt)
+		update.setSeverity(GetMattermostPostStatus(post.Metadata.Reactions, app.botID))
+
+		if post.IsPinned {
+			pinnedUpdates = append(pinnedUpdates, update)
+		} else {
+			updates = append(updates, update)
+		}
+	}
+
+	recordUnpinned(app.page.setUpdates(pinnedUpdates, updates), time.Now())
+	return nil
+}
+
+// Pass-Thru the interface to the Page object
+func (app *CSPMattermost) StatusPage(gin *gin.Context) {
+	app.page.statusPage(gin)
+}
+
+func (app *CSPMattermost) Page() *CSPPage {
+	return app.page
+}
+
+func (app *CSPMattermost) SendReminders(now bool) error {
+	fmt.Println("Sending unpin reminders...")
+	var pinnedMessageLinks []ReminderInfo
+	for _, post := range app.channelHistory {
+		// Don't send reminders for posts that don't mention the bot.
+		// That way, we can still pin posts.
+		if post.RootID != "" || !post.IsPinned || !app.botActionablyMentioned(post.Message) {
+			continue
+		}
+
+		// Don't bother if the post hasn't been up long enough for its
+		// severity
+		posted := time.UnixMilli(post.CreateAt)
+		status := GetMattermostPostStatus(post.Metadata.Reactions, app.botID)
+		if !reminderDue(status, time.Since(posted), now) {
+			fmt.Println("Message not pinned for long enough. Ignoring.")
+			continue
+		}
+
+		author, err := app.client.getUser(post.UserID)
+		if err != nil {
+			return err
+		}
+		pinnedMessageLinks = append(pinnedMessageLinks, ReminderInfo{author.Username, app.permalink(post.ID), posted, status, post.ID})
+		fmt.Println("Found message.")
+	}
+
+	if len(pinnedMessageLinks) == 0 {
+		fmt.Println("No messages pinned.")
+		return nil
+	}
+
+	// Send summary message
+	summaryMessage := fmt.Sprintln("Hello, Admins.\nThe following messages are currently pinned.")
+	for _, m := range pinnedMessageLinks {
+		// The status is a severity, but the summary wants its emoji
+		var parsedStatus string
+		if emoji := severityEmoji(m.status); emoji == "" {
+			parsedStatus = "•"
+		} else {
+			parsedStatus = fmt.Sprintf(":%s:", emoji)
+		}
+		summaryMessage += fmt.Sprintf("%s @%s [Since %s](%s)\n\n", parsedStatus, m.userID, timeToHumanTime(m.at), m.link)
+	}
+
+	summaryMessage += fmt.Sprintf("It might be time to unpin them if they are no longer relevant.")
+
+	_, err := app.client.createPost(config().MattermostStatusChannelID, "", summaryMessage)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("success.")
+	return nil
+}
+
+// Posts a message from us in the status channel
+func (app *CSPMattermost) Announce(message string) error {
+	_, err := app.client.createPost(config().MattermostStatusChannelID, "", message)
+	return err
+}
+
+// Takes an update off the page and says why in its thread
+func (app *CSPMattermost) Unpin(updateID string, note string) error {
+	err := app.client.unpinPost(updateID)
+	if err != nil {
+		return err
+	}
+	_, err = app.client.createPost(config().MattermostStatusChannelID, updateID, note)
+	return err
+}
+
+func (app *CSPMattermost) Run() {
+	for {
+		fmt.Println("Connecting to Mattermost...")
+		conn, err := app.client.connectWebsocket()
+		if err != nil {
+			fmt.Println("Connection failed. Retrying later...")
+			log.Println(err)
+			time.Sleep(10 * time.Second)
+			continue
+		}
+		fmt.Println("Connected to Mattermost.")
+
+		for {
+			var evt mattermostEvent
+			err = conn.ReadJSON(&evt)
+			if err != nil {
+				log.Println(err)
+				break
+			}
+			// Responses to our own requests over the socket have no event
+			if evt.Event == "" {
+				continue
+			}
+			log.Println("Got event:", evt.Event)
+			h := CSPMattermostEvtHandler{app, evt}
+			h.handleEvent()
+		}
+		conn.Close()
+	}
+}
+
+// Utility functions
+
+// Sync our cached Mattermost posts and re-build the page history
+func (app *CSPMattermost) refresh() {
+	err := app.getChannelHistory()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	err = app.BuildStatusPage()
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+func (app *CSPMattermost) getChannelHistory() (err error) {
+	c := config()
+	log.Println("Fetching channel history from: ", c.MattermostStatusChannelID)
+	limit, _ := strconv.Atoi(c.MattermostTruncation)
+	app.channelHistory, err = app.client.getChannelPosts(c.MattermostStatusChannelID, limit)
+	return err
+}
+
+func (app *CSPMattermost) permalink(postID string) string {
+	return fmt.Sprintf("%s/%s/pl/%s", strings.TrimSuffix(config().MattermostURL, "/"), app.teamName, postID)
+}
+
+// Ignore posts that don't mention us. Also, ignore posts that
+// mention us but are empty!
+func (app *CSPMattermost) botActionablyMentioned(message string) bool {
+	return mattermostMentionRegex(app.botUsername).MatchString(message) && app.stripBotMention(message) != ""
+}
+
+func (app *CSPMattermost) stripBotMention(message string) string {
+	return strings.TrimSpace(mattermostMentionRegex(app.botUsername).ReplaceAllString(message, ""))
+}
+
+// Mattermost renders ~channel-name as a link, so we should too.
+func (app *CSPMattermost) channelLinksToMarkdown(message string) string {
+	channelLinkRegex := regexp.MustCompile(`(^|\s)~([a-z0-9_-]+)`)
+	return channelLinkRegex.ReplaceAllString(
+		message,
+		fmt.Sprintf("$1[~$2](%s/%s/channels/$2)", strings.TrimSuffix(config().MattermostURL, "/"), app.teamName),
+	)
+}
+
+// Removes the bot's reactions from a post. Mattermost will complain if we
+// remove a reaction that isn't there, so only touch the ones we have.
+func (app *CSPMattermost) clearReactions(post mattermostPost, focusReactions []string) {
+	for _, reaction := range post.Metadata.Reactions {
+		if reaction.UserID != app.botID || !stringInSlice(focusReactions, reaction.EmojiName) {
+			continue
+		}
+		err := app.client.removeReaction(app.botID, post.ID, reaction.EmojiName)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// Payloads on the websocket are JSON strings nested in the event data
+func decodeMattermostEventData(evt mattermostEvent, key string, v any) error {
+	raw, ok := evt.Data[key].(string)
+	if !ok {
+		return fmt.Errorf("mattermost: event %s has no %s", evt.Event, key)
+	}
+	return json.Unmarshal([]byte(raw), v)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Just enough of the Mattermost v4 API to run the status page. We don't pull
+// in the official client since it drags the entire server along with it.
+type mattermostClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+type mattermostUser struct {
+	ID        string `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Nickname  string `json:"nickname"`
+}
+
+type mattermostChannel struct {
+	ID          string `json:"id"`
+	TeamID      string `json:"team_id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type mattermostTeam struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type mattermostReaction struct {
+	UserID    string `json:"user_id"`
+	PostID    string `json:"post_id"`
+	EmojiName string `json:"emoji_name"`
+}
+
+type mattermostPost struct {
+	ID        string `json:"id"`
+	CreateAt  int64  `json:"create_at"`
+	DeleteAt  int64  `json:"delete_at"`
+	UserID    string `json:"user_id"`
+	ChannelID string `json:"channel_id"`
+	RootID    string `json:"root_id"`
+	Message   string `json:"message"`
+	IsPinned  bool   `json:"is_pinned"`
+	Metadata  struct {
+		Reactions []mattermostReaction `json:"reactions"`
+	} `json:"metadata"`
+}
+
+type mattermostPostList struct {
+	Order []string                   `json:"order"`
+	Posts map[string]*mattermostPost `json:"posts"`
+}
+
+// Events come off the websocket with their payloads JSON-encoded a second
+// time inside of data.
+type mattermostEvent struct {
+	Event     string            `json:"event"`
+	Data      map[string]any    `json:"data"`
+	Broadcast map[string]string `json:"broadcast"`
+}
+
+func newMattermostClient(baseURL string, token string) *mattermostClient {
+	return &mattermostClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{},
+	}
+}
+
+func (c *mattermostClient) do(method string, path string, body any, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+"/api/v4"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("mattermost: %s %s returned %d: %s", method, path, resp.StatusCode, msg)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (c *mattermostClient) getMe() (user mattermostUser, err error) {
+	err = c.do(http.MethodGet, "/users/me", nil, &user)
+	return user, err
+}
+
+func (c *mattermostClient) getUser(userID string) (user mattermostUser, err error) {
+	err = c.do(http.MethodGet, "/users/"+userID, nil, &user)
+	return user, err
+}
+
+func (c *mattermostClient) getChannel(channelID string) (channel mattermostChannel, err error) {
+	err = c.do(http.MethodGet, "/channels/"+channelID, nil, &channel)
+	return channel, err
+}
+
+func (c *mattermostClient) getTeam(teamID string) (team mattermostTeam, err error) {
+	err = c.do(http.MethodGet, "/teams/"+teamID, nil, &team)
+	return team, err
+}
+
+func (c *mattermostClient) getPost(postID string) (post mattermostPost, err error) {
+	err = c.do(http.MethodGet, "/posts/"+postID, nil, &post)
+	return post, err
+}
+
+// Returns the newest posts in a channel, newest first.
+func (c *mattermostClient) getChannelPosts(channelID string, perPage int) (posts []*mattermostPost, err error) {
+	query := url.Values{}
+	if perPage > 0 {
+		query.Set("per_page", fmt.Sprint(perPage))
+	}
+	var list mattermostPostList
+	err = c.do(http.MethodGet, "/channels/"+channelID+"/posts?"+query.Encode(), nil, &list)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range list.Order {
+		if post, ok := list.Posts[id]; ok {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (c *mattermostClient) createPost(channelID string, rootID string, message string) (post mattermostPost, err error) {
+	err = c.do(http.MethodPost, "/posts", mattermostPost{
+		ChannelID: channelID,
+		RootID:    rootID,
+		Message:   message,
+	}, &post)
+	return post, err
+}
+
+func (c *mattermostClient) deletePost(postID string) error {
+	return c.do(http.MethodDelete, "/posts/"+postID, nil, nil)
+}
+
+func (c *mattermostClient) pinPost(postID string) error {
+	return c.do(http.MethodPost, "/posts/"+postID+"/pin", nil, nil)
+}
+
+func (c *mattermostClient) unpinPost(postID string) error {
+	return c.do(http.MethodPost, "/posts/"+postID+"/unpin", nil, nil)
+}
+
+func (c *mattermostClient) addReaction(userID string, postID string, emoji string) error {
+	return c.do(http.MethodPost, "/reactions", mattermostReaction{
+		UserID:    userID,
+		PostID:    postID,
+		EmojiName: emoji,
+	}, nil)
+}
+
+func (c *mattermostClient) removeReaction(userID string, postID string, emoji string) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/users/%s/posts/%s/reactions/%s", userID, postID, emoji), nil, nil)
+}
+
+// Opens the event stream. The caller is responsible for closing it.
+func (c *mattermostClient) connectWebsocket() (*websocket.Conn, error) {
+	wsURL := strings.Replace(c.baseURL, "http", "ws", 1) + "/api/v4/websocket"
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+c.token)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	return conn, err
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"log"
+)
+
+type CSPMattermostEvtHandler struct {
+	*CSPMattermost
+	evt mattermostEvent
+}
+
+func (h *CSPMattermostEvtHandler) handleEvent() {
+	switch h.evt.Event {
+	case "hello":
+		log.Println("Mattermost said hello.")
+	case "posted":
+		h.handlePostedEvent()
+	case "post_edited", "post_deleted":
+		// Pinning and unpinning also come through as edits
+		h.handlePostChangedEvent()
+	case "reaction_added":
+		h.handleReactionAddedEvent()
+	case "reaction_removed":
+		h.handleReactionRemovedEvent()
+	default:
+		log.Println("no handler for event of given type")
+	}
+}
+
+func (h *CSPMattermostEvtHandler) handlePostedEvent() {
+	c := config()
+	var post mattermostPost
+	err := decodeMattermostEventData(h.evt, "post", &post)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if post.ChannelID != c.MattermostStatusChannelID || post.UserID == h.botID {
+		return
+	}
+
+	// If the bot was mentioned in this post, then we should probably
+	// re-build the page, and if not, we should bail.
+	if post.RootID != "" || !h.botActionablyMentioned(post.Message) {
+		return
+	}
+	defer h.refresh()
+
+	log.Printf("Got mentioned. Post ID is: %s\n", post.ID)
+
+	author, err := h.client.getUser(post.UserID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	prompt, err := h.client.createPost(c.MattermostStatusChannelID, post.ID, CreateMattermostUpdateResponseMsg(author.Username))
+	if err != nil {
+		log.Printf("Error posting prompt message: %s", err)
+		return
+	}
+	h.prompts[post.ID] = prompt.ID
+}
+
+func (h *CSPMattermostEvtHandler) handlePostChangedEvent() {
+	var post mattermostPost
+	err := decodeMattermostEventData(h.evt, "post", &post)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if post.ChannelID != config().MattermostStatusChannelID {
+		return
+	}
+	if h.evt.Event == "post_deleted" {
+		delete(h.prompts, post.ID)
+	}
+	h.refresh()
+}
+
+func (h *CSPMattermostEvtHandler) handleReactionAddedEvent() {
+	c := config()
+	var reaction mattermostReaction
+	err := decodeMattermostEventData(h.evt, "reaction", &reaction)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	emoji := reaction.EmojiName
+	if reaction.UserID == h.botID || (!isRelevantReaction(emoji) && emoji != c.PinEmoji) {
+		return
+	}
+
+	post, err := h.client.getPost(reaction.PostID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if post.ChannelID != c.MattermostStatusChannelID || post.RootID != "" || !h.botActionablyMentioned(post.Message) {
+		return
+	}
+	defer h.refresh()
+
+	if emoji == c.PinEmoji {
+		log.Println("Will pin message")
+		err = h.client.pinPost(post.ID)
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	// If necessary, remove a conflicting reaction
+	h.clearReactions(post, severityEmojis())
+	// Mirror the reaction on the post
+	err = h.client.addReaction(h.botID, post.ID, emoji)
+	if err != nil {
+		log.Println(err)
+	}
+
+	// Somebody answered the prompt, so we don't need it anymore.
+	if promptID, ok := h.prompts[post.ID]; ok {
+		err = h.client.deletePost(promptID)
+		if err != nil {
+			log.Println(err)
+		}
+		delete(h.prompts, post.ID)
+	}
+}
+
+func (h *CSPMattermostEvtHandler) handleReactionRemovedEvent() {
+	c := config()
+	var reaction mattermostReaction
+	err := decodeMattermostEventData(h.evt, "reaction", &reaction)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if reaction.UserID == h.botID || !isRelevantReaction(reaction.EmojiName) {
+		return
+	}
+	post, err := h.client.getPost(reaction.PostID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if post.ChannelID != c.MattermostStatusChannelID {
+		return
+	}
+	h.clearReactions(post, []string{reaction.EmojiName})
+	h.refresh()
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A tiny stand-in for a Mattermost server that serves a fixed channel and
+// records what the bot asks it to do.
+type fakeMattermost struct {
+	mu       sync.Mutex
+	posts    map[string]*mattermostPost
+	order    []string
+	requests []string
+	created  []string
+}
+
+func newFakeMattermost(posts ...*mattermostPost) *fakeMattermost {
+	fake := &fakeMattermost{posts: make(map[string]*mattermostPost)}
+	for _, post := range posts {
+		fake.posts[post.ID] = post
+		fake.order = append(fake.order, post.ID)
+	}
+	return fake
+}
+
+func (f *fakeMattermost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/api/v4")
+	f.requests = append(f.requests, r.Method+" "+path)
+
+	respond := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case path == "/users/me":
+		respond(mattermostUser{ID: "bot", Username: "status"})
+	case strings.HasPrefix(path, "/users/") && !strings.Contains(path, "/posts/"):
+		respond(mattermostUser{ID: strings.TrimPrefix(path, "/users/"), Username: "volunteer", FirstName: "Some", LastName: "Volunteer"})
+	case path == "/channels/status":
+		respond(mattermostChannel{ID: "status", TeamID: "team", Name: "status"})
+	case path == "/teams/team":
+		respond(mattermostTeam{ID: "team", Name: "mesh"})
+	case path == "/channels/status/posts":
+		respond(mattermostPostList{Order: f.order, Posts: f.posts})
+	case strings.HasPrefix(path, "/posts/") && r.Method == http.MethodGet:
+		respond(f.posts[strings.TrimPrefix(path, "/posts/")])
+	case path == "/posts" && r.Method == http.MethodPost:
+		var post mattermostPost
+		_ = json.NewDecoder(r.Body).Decode(&post)
+		f.created = append(f.created, post.Message)
+		respond(mattermostPost{ID: "prompt"})
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func newTestCSPMattermost(t *testing.T, fake *fakeMattermost) *CSPMattermost {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	withConfig(t, func(c *Config) {
+		c.MattermostURL = server.URL
+		c.MattermostToken = "token"
+		c.MattermostStatusChannelID = "status"
+		c.SeverityLevels = parseSeverityLevels("ok|OK/Info|ok|white_check_mark\nwarn|Warning|warn|warning\nerror|Critical|error|fire")
+		c.PinEmoji = "pushpin"
+	})
+
+	app, err := NewCSPMattermost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &app
+}
+
+func TestMattermostBuildStatusPage(t *testing.T) {
+	pinned := &mattermostPost{ID: "p1", UserID: "u1", ChannelID: "status", Message: "@status the backbone is down", IsPinned: true}
+	pinned.Metadata.Reactions = []mattermostReaction{{UserID: "bot", PostID: "p1", EmojiName: "fire"}}
+	fake := newFakeMattermost(
+		pinned,
+		&mattermostPost{ID: "p2", UserID: "u1", ChannelID: "status", Message: "@status all better now"},
+		&mattermostPost{ID: "p3", UserID: "u1", ChannelID: "status", Message: "just chatting"},
+		&mattermostPost{ID: "p4", UserID: "u1", ChannelID: "status", RootID: "p1", Message: "@status thanks"},
+		&mattermostPost{ID: "p5", UserID: "u1", ChannelID: "status", Message: "@status"},
+	)
+	app := newTestCSPMattermost(t, fake)
+
+	if len(app.page.pinnedUpdates) != 1 {
+		t.Fatalf("Expected 1 pinned update, got %d", len(app.page.pinnedUpdates))
+	}
+	if len(app.page.updates) != 1 {
+		t.Fatalf("Expected 1 update, got %d", len(app.page.updates))
+	}
+	update := app.page.pinnedUpdates[0]
+	if update.BackgroundClass != "list-group-item-danger" {
+		t.Errorf("Expected the pinned update to be critical, got '%s'", update.BackgroundClass)
+	}
+	if strings.Contains(string(update.HTML), "@status") {
+		t.Errorf("Bot mention was not stripped: '%s'", update.HTML)
+	}
+	if update.SentBy != "Some Volunteer" {
+		t.Errorf("Expected update to be sent by 'Some Volunteer', got '%s'", update.SentBy)
+	}
+}
+
+func TestMattermostReactionMirrored(t *testing.T) {
+	post := &mattermostPost{ID: "p1", UserID: "u1", ChannelID: "status", Message: "@status the backbone is down"}
+	post.Metadata.Reactions = []mattermostReaction{{UserID: "bot", PostID: "p1", EmojiName: "fire"}}
+	fake := newFakeMattermost(post)
+	app := newTestCSPMattermost(t, fake)
+	app.prompts["p1"] = "prompt"
+
+	reaction, _ := json.Marshal(mattermostReaction{UserID: "u1", PostID: "p1", EmojiName: "warning"})
+	h := CSPMattermostEvtHandler{app, mattermostEvent{
+		Event: "reaction_added",
+		Data:  map[string]any{"reaction": string(reaction)},
+	}}
+	fake.requests = nil
+	h.handleEvent()
+
+	for _, expected := range []string{
+		"DELETE /users/bot/posts/p1/reactions/fire",
+		"POST /reactions",
+		"DELETE /posts/prompt",
+	} {
+		if !stringInSlice(fake.requests, expected) {
+			t.Errorf("Expected request '%s', got %v", expected, fake.requests)
+		}
+	}
+	if _, ok := app.prompts["p1"]; ok {
+		t.Errorf("Prompt was not forgotten after being answered")
+	}
+}
+
+func TestMattermostReminderEmoji(t *testing.T) {
+	post := &mattermostPost{ID: "p1", UserID: "u1", ChannelID: "status", Message: "@status the backbone is down", IsPinned: true}
+	post.Metadata.Reactions = []mattermostReaction{{UserID: "bot", PostID: "p1", EmojiName: "fire"}}
+	fake := newFakeMattermost(post)
+	app := newTestCSPMattermost(t, fake)
+
+	err := app.SendReminders(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.created) != 1 {
+		t.Fatalf("Expected one summary, got %d posts", len(fake.created))
+	}
+	if !strings.Contains(fake.created[0], ":fire:") || strings.Contains(fake.created[0], ":error:") {
+		t.Errorf("Expected the severity's emoji in the summary, got %q", fake.created[0])
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+func mattermostMentionRegex(username string) *regexp.Regexp {
+	return regexp.MustCompile(`@` + regexp.QuoteMeta(username) + `\b`)
+}
+
+func mattermostDisplayName(user mattermostUser) string {
+	if user.Nickname != "" {
+		return user.Nickname
+	}
+	if name := strings.TrimSpace(user.FirstName + " " + user.LastName); name != "" {
+		return name
+	}
+	return user.Username
+}
+
+// Mattermost names its emoji the same way Slack does, so we can share the
+// configured reactions with it.
+func GetMattermostPostStatus(reactions []mattermostReaction, botID string) string {
+	for _, reaction := range reactions {
+		// Only take action on our reactions
+		if reaction.UserID != botID {
+			continue
+		}
+
+		// Use the first reaction sent by the bot that we find
+		if severity := emojiSeverity(reaction.EmojiName); severity != "" {
+			return severity
+		}
+	}
+	return ""
+}
+
+// Function to build the message the bot sends in response to being pinged with
+// a new status update. Mattermost buttons need an outgoing webhook to talk
+// back to us, so we use reactions instead.
+func CreateMattermostUpdateResponseMsg(user string) string {
+	var levels string
+	for _, level := range severityLevelsWorstFirst() {
+		if level.Emoji != "" {
+			levels += fmt.Sprintf("- :%s: %s\n", level.Emoji, level.Label)
+		}
+	}
+	return fmt.Sprintf(
+		"@%s I see you have posted a new message to the support page. What kind of alert is this? React to your message with:\n"+
+			"%s\n"+
+			"and with :%s: to pin it to the status page. **Warning: this alert is live immediately!**",
+		user,
+		levels,
+		config().PinEmoji,
+	)
+}
@@ -0,0 +1,49 @@
+package main
+
+// How things are overall, going by the worst thing that's pinned. Pins that
+// don't mean trouble, like announcements, leave everything operational.
+type OverallStatus struct {
+	Severity   string
+	Impact     string
+	Headline   string
+	AlertClass string
+	Icon       string
+	Color      string
+}
+
+func overallStatus(pinnedUpdates []StatusUpdate) (overall OverallStatus) {
+	var worst SeverityLevel
+	for _, update := range pinnedUpdates {
+		if level, ok := severityLevel(update.Severity); ok && level.Rank > worst.Rank {
+			worst = level
+		}
+	}
+
+	switch worst.Impact {
+	case SeverityWarn:
+		overall.Headline = "Degraded performance"
+		overall.AlertClass = "alert-warning"
+	case SeverityError:
+		overall.Headline = "Partial outage"
+		overall.AlertClass = "alert-danger"
+	default:
+		overall.Impact = SeverityOK
+		overall.Headline = config().NominalMessage
+		if overall.Headline == "" {
+			overall.Headline = "All systems operational"
+		}
+		overall.AlertClass = "alert-success"
+		overall.Icon = impactIcon(SeverityOK)
+		return overall
+	}
+
+	overall.Severity = worst.Name
+	overall.Impact = worst.Impact
+	overall.Icon = worst.Icon
+	overall.Color = worst.Color
+	// Custom levels say what they mean themselves
+	if worst.Name != worst.Impact {
+		overall.Headline = worst.Label
+	}
+	return overall
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestOverallStatus(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.NominalMessage = ""
+		c.SeverityLevels = defaultSeverityLevels(nil)
+	})
+
+	update := func(severity string) (u StatusUpdate) {
+		u.setSeverity(severity)
+		return u
+	}
+
+	if overall := overallStatus(nil); overall.Headline != "All systems operational" {
+		t.Errorf("Expected nothing pinned to be operational, got %+v", overall)
+	}
+	if overall := overallStatus([]StatusUpdate{update(SeverityOK), update("")}); overall.Impact != SeverityOK || overall.AlertClass != "alert-success" {
+		t.Errorf("Expected an OK pin to leave everything operational, got %+v", overall)
+	}
+	if overall := overallStatus([]StatusUpdate{update(SeverityWarn), update(SeverityError), update(SeverityOK)}); overall.Severity != SeverityError || overall.Headline != "Partial outage" {
+		t.Errorf("Expected the worst pin to win, got %+v", overall)
+	}
+
+	withConfig(t, func(c *Config) {
+		c.SeverityLevels = parseSeverityLevels("info|Info|ok\ndegraded|Degraded performance|warn\nmajor_outage|Major outage|error|fire||#f8d7da")
+	})
+	if overall := overallStatus([]StatusUpdate{update("degraded"), update("major_outage")}); overall.Headline != "Major outage" || overall.Color != "#f8d7da" {
+		t.Errorf("Expected custom levels to use their own label and color, got %+v", overall)
+	}
+}
@@ -2,38 +2,308 @@ package main
 
 import (
 	"html/template"
+	"log"
 	"net/http"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type StatusUpdate struct {
-	HTML            template.HTML
-	SentBy          string
-	TimeStamp       string
-	BackgroundClass string
-	IconFilename    string
+	ID                 string
+	HTML               template.HTML
+	SentBy             string
+	Origin             string
+	Component          string
+	Components         []string
+	Severity           string
+	SeverityLabel      string
+	State              string
+	StateHistory       []StateChange
+	Replies            []StatusReply
+	ExpectedResolution time.Time
+	Time               time.Time
+	BackgroundClass    string
+	Color              string
+	IconFilename       string
+}
+
+// A follow-up posted in the thread under an update
+type StatusReply struct {
+	HTML   template.HTML
+	SentBy string
+	State  string
+	Time   time.Time
+}
+
+func (reply StatusReply) TimeStamp() string {
+	return timeToHumanTime(reply.Time)
+}
+
+func (reply StatusReply) ISOTime() string {
+	return isoTime(reply.Time)
+}
+
+func (reply StatusReply) StateLabel() string {
+	return incidentStateLabel(reply.State)
+}
+
+func (reply StatusReply) StateBadgeClass() string {
+	return stateBadgeClass(reply.State)
+}
+
+func (update StatusUpdate) TimeStamp() string {
+	return timeToHumanTime(update.Time)
+}
+
+func (update StatusUpdate) ISOTime() string {
+	return isoTime(update.Time)
+}
+
+func (update StatusUpdate) ExpectedResolutionTimeStamp() string {
+	return timeToHumanTime(update.ExpectedResolution)
+}
+
+func (update StatusUpdate) ExpectedResolutionISOTime() string {
+	return isoTime(update.ExpectedResolution)
+}
+
+// Sets the card colors and icon for the given severity
+func (update *StatusUpdate) setSeverity(severity string) {
+	update.Severity = severity
+	level, ok := severityLevel(severity)
+	if !ok {
+		return
+	}
+	update.SeverityLabel = level.Label
+	update.Color = level.Color
+	update.IconFilename = level.Icon
+	switch level.Impact {
+	case SeverityOK:
+		update.BackgroundClass = "list-group-item-success"
+	case SeverityWarn:
+		update.BackgroundClass = "list-group-item-warning"
+	case SeverityError:
+		update.BackgroundClass = "list-group-item-danger"
+	}
 }
 
 type CSPPage struct {
+	// Backends rebuild the page from their own event loops while the web
+	// server reads it, so guard the slices.
+	mu            sync.RWMutex
 	updates       []StatusUpdate
 	pinnedUpdates []StatusUpdate
+
+	// Only worth labelling where updates came from if there's more than one
+	// place they could have come from.
+	showOrigin bool
+
+	// The order components show up in on the page, as configured
+	componentOrder []string
+
+	// Updates that have a preview link, keyed by preview token. Drafts and
+	// updates awaiting approval are only ever seen through these.
+	previews map[string]UpdatePreview
+}
+
+// How an update looks on the page, or would look if it were live
+type UpdatePreview struct {
+	Update StatusUpdate
+	Pinned bool
+	Live   bool
+}
+
+// A group of pinned updates that all belong to the same component
+type PageSection struct {
+	Name    string
+	Updates []StatusUpdate
+}
+
+// Groups pinned updates by the component they belong to. Updates without one
+// go first, then components in the order they were configured, then anything
+// else we didn't know about.
+func (page *CSPPage) sections(pinnedUpdates []StatusUpdate) (sections []PageSection) {
+	order := append([]string{""}, page.componentOrder...)
+	for _, update := range pinnedUpdates {
+		if !stringInSlice(order, update.Component) {
+			order = append(order, update.Component)
+		}
+	}
+	for _, name := range order {
+		var section PageSection
+		section.Name = name
+		for _, update := range pinnedUpdates {
+			if update.Component == name {
+				section.Updates = append(section.Updates, update)
+			}
+		}
+		if len(section.Updates) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// Swap in a freshly built set of updates. Hands back the ones that were
+// pinned last time and aren't anymore.
+func (page *CSPPage) setUpdates(pinnedUpdates []StatusUpdate, updates []StatusUpdate) (unpinned []string) {
+	page.mu.Lock()
+	defer page.mu.Unlock()
+
+	wasPinned := make(map[string]bool)
+	for _, update := range page.pinnedUpdates {
+		wasPinned[update.ID] = update.ID != ""
+	}
+	for _, update := range updates {
+		if wasPinned[update.ID] {
+			unpinned = append(unpinned, update.ID)
+		}
+	}
+	page.pinnedUpdates = pinnedUpdates
+	page.updates = updates
+	return unpinned
+}
+
+// How long we remember when things came unpinned
+const unpinnedKeep = 90 * 24 * time.Hour
+
+// Nobody tells us when something gets unpinned, but that's when the incident
+// was over, so keep track of it ourselves. Only the backends call this, so
+// the combined page doesn't write everything down twice.
+func recordUnpinned(updateIDs []string, now time.Time) {
+	if len(updateIDs) == 0 {
+		return
+	}
+	for _, updateID := range updateIDs {
+		err := store.recordUnpinned(updateID, now)
+		if err != nil {
+			log.Println(err)
+		}
+		err = store.recordPinned(updateID, false, now)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	err := store.pruneUnpinned(now.Add(-unpinnedKeep))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (page *CSPPage) setPreviews(previews map[string]UpdatePreview) {
+	page.mu.Lock()
+	defer page.mu.Unlock()
+	page.previews = previews
+}
+
+func (page *CSPPage) preview(token string) (preview UpdatePreview, ok bool) {
+	page.mu.RLock()
+	defer page.mu.RUnlock()
+	preview, ok = page.previews[token]
+	return preview, ok
+}
+
+func (page *CSPPage) snapshot() (pinnedUpdates []StatusUpdate, updates []StatusUpdate) {
+	page.mu.RLock()
+	defer page.mu.RUnlock()
+	return restyled(page.pinnedUpdates), restyled(page.updates)
+}
+
+// Copies of the updates, dressed in the severity levels as they are now.
+// They might have been reloaded since the page was built.
+func restyled(updates []StatusUpdate) []StatusUpdate {
+	copies := make([]StatusUpdate, len(updates))
+	for i, update := range updates {
+		update.setSeverity(update.Severity)
+		copies[i] = update
+	}
+	return copies
+}
+
+// What's on the page right now, including any scheduled maintenance
+func (page *CSPPage) current() (pinnedUpdates []StatusUpdate, updates []StatusUpdate) {
+	pinnedUpdates, updates = page.snapshot()
+	return withMaintenance(pinnedUpdates, updates, time.Now())
+}
+
+// Combine the pages from several backends into one, newest updates first.
+func mergePages(pages []*CSPPage) (pinnedUpdates []StatusUpdate, updates []StatusUpdate) {
+	for _, page := range pages {
+		pinned, unpinned := page.snapshot()
+		pinnedUpdates = append(pinnedUpdates, pinned...)
+		updates = append(updates, unpinned...)
+	}
+	sortNewestFirst(pinnedUpdates)
+	sortNewestFirst(updates)
+	return pinnedUpdates, updates
+}
+
+func mergePreviews(pages []*CSPPage) map[string]UpdatePreview {
+	previews := make(map[string]UpdatePreview)
+	for _, page := range pages {
+		page.mu.RLock()
+		for token, preview := range page.previews {
+			previews[token] = preview
+		}
+		page.mu.RUnlock()
+	}
+	return previews
+}
+
+func sortNewestFirst(updates []StatusUpdate) {
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].Time.After(updates[j].Time)
+	})
 }
 
 func (page *CSPPage) statusPage(c *gin.Context) {
-	c.HTML(
-		http.StatusOK,
-		"index.html",
-		gin.H{
-			"HelpMessage":    template.HTML(config.HelpMessage),
-			"PinnedStatuses": page.pinnedUpdates,
-			"StatusUpdates":  page.updates,
-			"Org":            config.OrgName,
-			"Logo":           config.LogoURL,
-			"Favicon":        config.FaviconURL,
-			"NominalMessage": config.NominalMessage,
-		},
-	)
+	pinnedUpdates, updates := page.current()
+	c.HTML(http.StatusOK, "index.html", page.templateData(pinnedUpdates, updates))
+}
+
+// Shows the page as it would look with the update on it, so that whoever
+// wrote it can see how it came out before anyone else does
+func (page *CSPPage) previewPage(c *gin.Context) {
+	preview, ok := page.preview(c.Param("token"))
+	if !ok {
+		c.String(http.StatusNotFound, "No preview here. It may have scrolled off the page.")
+		return
+	}
+	pinnedUpdates, updates := page.current()
+	if !preview.Live {
+		if preview.Pinned {
+			pinnedUpdates = append([]StatusUpdate{preview.Update}, pinnedUpdates...)
+			sortNewestFirst(pinnedUpdates)
+		} else {
+			updates = append([]StatusUpdate{preview.Update}, updates...)
+			sortNewestFirst(updates)
+		}
+	}
+	data := page.templateData(pinnedUpdates, updates)
+	data["Preview"] = preview
+	c.HTML(http.StatusOK, "index.html", data)
+}
+
+func (page *CSPPage) templateData(pinnedUpdates []StatusUpdate, updates []StatusUpdate) gin.H {
+	c := config()
+	return gin.H{
+		"HelpMessage":     template.HTML(c.HelpMessage),
+		"PinnedStatuses":  pinnedUpdates,
+		"PinnedSections":  page.sections(pinnedUpdates),
+		"ComponentGroups": componentStatuses(pinnedUpdates),
+		"StatusUpdates":   updates,
+		"ShowOrigin":      page.showOrigin,
+		"Org":             c.OrgName,
+		"Logo":            c.LogoURL,
+		"Favicon":         c.FaviconURL,
+		"NavLinks":        c.NavLinks,
+		"FooterSections":  c.FooterSections,
+		"Overall":         overallStatus(pinnedUpdates),
+		"Maintenance":     upcomingMaintenance(),
+	}
 }
 
 func health(c *gin.Context) {
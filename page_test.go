@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergePagesNewestFirst(t *testing.T) {
+	now := time.Now()
+	ours := &CSPPage{}
+	ours.setUpdates(
+		[]StatusUpdate{{Origin: "ours", Time: now.Add(-2 * time.Hour)}},
+		[]StatusUpdate{{Origin: "ours", Time: now.Add(-3 * time.Hour)}},
+	)
+	theirs := &CSPPage{}
+	theirs.setUpdates(
+		[]StatusUpdate{{Origin: "theirs", Time: now.Add(-1 * time.Hour)}},
+		[]StatusUpdate{{Origin: "theirs", Time: now.Add(-4 * time.Hour)}, {Origin: "theirs", Time: now}},
+	)
+
+	pinned, updates := mergePages([]*CSPPage{ours, theirs})
+	if len(pinned) != 2 || len(updates) != 3 {
+		t.Fatalf("Expected 2 pinned and 3 updates, got %d and %d", len(pinned), len(updates))
+	}
+	if pinned[0].Origin != "theirs" || pinned[1].Origin != "ours" {
+		t.Errorf("Pinned updates are out of order: %+v", pinned)
+	}
+	for i := 1; i < len(updates); i++ {
+		if updates[i].Time.After(updates[i-1].Time) {
+			t.Errorf("Updates are out of order at %d: %+v", i, updates)
+		}
+	}
+}
+
+func TestRecordUnpinned(t *testing.T) {
+	defer func(s *CSPStore) { store = s }(store)
+	store = &CSPStore{}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.recordUnpinned("old", now.Add(-unpinnedKeep-time.Hour))
+
+	page := &CSPPage{}
+	page.setUpdates([]StatusUpdate{{ID: "a"}, {ID: "b"}}, nil)
+	unpinned := page.setUpdates([]StatusUpdate{{ID: "b"}}, []StatusUpdate{{ID: "a"}})
+	if len(unpinned) != 1 || unpinned[0] != "a" {
+		t.Fatalf("Expected a to have come unpinned, got %v", unpinned)
+	}
+
+	recordUnpinned(unpinned, now)
+	if at, ok := store.unpinnedAt("a"); !ok || !at.Equal(now) {
+		t.Errorf("Expected a to be unpinned at %s, got %s", now, at)
+	}
+	if _, ok := store.unpinnedAt("old"); ok {
+		t.Error("Expected long-gone unpins to be forgotten")
+	}
+}
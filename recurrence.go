@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A small subset of RFC 5545 recurrence rules, enough for maintenance rotas.
+// Rules look like
+//
+//	DTSTART;TZID=America/New_York:20240312T020000 RRULE:FREQ=MONTHLY;BYDAY=2TU
+//
+// FREQ can be DAILY, WEEKLY or MONTHLY. INTERVAL, UNTIL and BYDAY (with
+// ordinals like 2TU or -1FR for monthly rules) are supported, nothing else is.
+type Recurrence struct {
+	Start    time.Time
+	Freq     string
+	Interval int
+	ByDay    []recurrenceDay
+	Until    time.Time
+}
+
+// A weekday from BYDAY. N picks which one in the month, counting from the end
+// if it's negative. Zero means all of them.
+type recurrenceDay struct {
+	N   int
+	Day time.Weekday
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Occurrences further out than this aren't worth looking for
+const recurrenceSearchDays = 3 * 366
+
+func parseRecurrence(value string) (rule Recurrence, err error) {
+	rule.Interval = 1
+	for _, field := range strings.Fields(value) {
+		name, params, _ := strings.Cut(field, ":")
+		switch {
+		case strings.HasPrefix(name, "DTSTART"):
+			rule.Start, err = parseRRuleTime(name, params)
+			if err != nil {
+				return rule, err
+			}
+		case name == "RRULE":
+			err = rule.parseParts(params)
+			if err != nil {
+				return rule, err
+			}
+		default:
+			return rule, fmt.Errorf("unsupported recurrence field '%s'", name)
+		}
+	}
+	if rule.Start.IsZero() {
+		return rule, errors.New("recurrence needs a DTSTART")
+	}
+	if rule.Freq == "" {
+		return rule, errors.New("recurrence needs an RRULE with a FREQ")
+	}
+	return rule, nil
+}
+
+// Times are either UTC (ending in Z), in the given TZID, or in our own
+// time zone
+func parseRRuleTime(name string, value string) (time.Time, error) {
+	location := displayLocation()
+	if _, tzid, found := strings.Cut(name, ";TZID="); found {
+		var err error
+		location, err = time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+	return time.ParseInLocation("20060102T150405", value, location)
+}
+
+func (rule *Recurrence) parseParts(parts string) (err error) {
+	for _, part := range strings.Split(parts, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY":
+				rule.Freq = value
+			default:
+				return fmt.Errorf("unsupported FREQ '%s'", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err != nil || rule.Interval < 1 {
+				return fmt.Errorf("bad INTERVAL '%s'", value)
+			}
+		case "UNTIL":
+			rule.Until, err = parseRRuleTime("UNTIL", value)
+			if err != nil {
+				return err
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				weekday, ok := rruleWeekdays[day[max(len(day)-2, 0):]]
+				if !ok {
+					return fmt.Errorf("bad BYDAY '%s'", day)
+				}
+				var n int
+				if ordinal := day[:len(day)-2]; ordinal != "" {
+					n, err = strconv.Atoi(ordinal)
+					if err != nil || n == 0 || n > 5 || n < -5 {
+						return fmt.Errorf("bad BYDAY '%s'", day)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, recurrenceDay{N: n, Day: weekday})
+			}
+		default:
+			return fmt.Errorf("unsupported RRULE part '%s'", key)
+		}
+	}
+	return nil
+}
+
+// The first occurrence that starts after the given time
+func (rule Recurrence) next(after time.Time) (time.Time, bool) {
+	location := rule.Start.Location()
+	day := dateOf(after.In(location))
+	if first := dateOf(rule.Start); day.Before(first) {
+		day = first
+	}
+	for i := 0; i < recurrenceSearchDays; i++ {
+		if rule.matches(day) {
+			start := time.Date(day.Year(), day.Month(), day.Day(), rule.Start.Hour(), rule.Start.Minute(), rule.Start.Second(), 0, location)
+			if !rule.Until.IsZero() && start.After(rule.Until) {
+				return time.Time{}, false
+			}
+			if start.After(after) {
+				return start, true
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, false
+}
+
+// Whether the rule lands on the given date, ignoring the time of day
+func (rule Recurrence) matches(day time.Time) bool {
+	first := dateOf(rule.Start)
+	switch rule.Freq {
+	case "DAILY":
+		return daysBetween(first, day)%rule.Interval == 0
+	case "WEEKLY":
+		// Weeks start on Monday, like RFC 5545 says
+		weeks := daysBetween(weekStart(first), weekStart(day)) / 7
+		if weeks%rule.Interval != 0 {
+			return false
+		}
+		if len(rule.ByDay) == 0 {
+			return day.Weekday() == first.Weekday()
+		}
+		for _, byDay := range rule.ByDay {
+			if byDay.Day == day.Weekday() {
+				return true
+			}
+		}
+		return false
+	case "MONTHLY":
+		months := (day.Year()-first.Year())*12 + int(day.Month()-first.Month())
+		if months%rule.Interval != 0 {
+			return false
+		}
+		if len(rule.ByDay) == 0 {
+			return day.Day() == first.Day()
+		}
+		daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		for _, byDay := range rule.ByDay {
+			if byDay.Day != day.Weekday() {
+				continue
+			}
+			switch {
+			case byDay.N == 0:
+				return true
+			case byDay.N > 0 && (day.Day()-1)/7+1 == byDay.N:
+				return true
+			case byDay.N < 0 && (daysInMonth-day.Day())/7+1 == -byDay.N:
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// Midnight UTC on the same calendar date, so days can be counted without DST
+// getting in the way
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from time.Time, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+func weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	cases := []struct {
+		rule     string
+		after    time.Time
+		expected time.Time
+	}{
+		// Second Tuesday of the month
+		{
+			"DTSTART;TZID=America/New_York:20240312T020000 RRULE:FREQ=MONTHLY;BYDAY=2TU",
+			time.Date(2024, 3, 12, 3, 0, 0, 0, newYork),
+			time.Date(2024, 4, 9, 2, 0, 0, 0, newYork),
+		},
+		// Every other Tuesday, across the end of daylight saving time
+		{
+			"DTSTART;TZID=America/New_York:20241022T020000 RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+			time.Date(2024, 10, 23, 0, 0, 0, 0, newYork),
+			time.Date(2024, 11, 5, 2, 0, 0, 0, newYork),
+		},
+		// Last Friday of the month
+		{
+			"DTSTART:20240101T230000Z RRULE:FREQ=MONTHLY;BYDAY=-1FR",
+			time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 23, 23, 0, 0, 0, time.UTC),
+		},
+		// Before it starts at all
+		{
+			"DTSTART:20240601T120000Z RRULE:FREQ=DAILY;INTERVAL=3",
+			time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, c := range cases {
+		rule, err := parseRecurrence(c.rule)
+		if err != nil {
+			t.Fatalf("Could not parse '%s': %s", c.rule, err)
+		}
+		next, ok := rule.next(c.after)
+		if !ok || !next.Equal(c.expected) {
+			t.Errorf("Next occurrence of '%s' after %s did not match.\nExpected: %s\nReceived: %s", c.rule, c.after, c.expected, next)
+		}
+	}
+}
+
+func TestRecurrenceUntil(t *testing.T) {
+	rule, err := parseRecurrence("DTSTART:20240101T000000Z RRULE:FREQ=WEEKLY;UNTIL=20240115T000000Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rule.next(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("Expected no occurrences after UNTIL")
+	}
+}
+
+func TestParseRecurringMaintenance(t *testing.T) {
+	windows := parseRecurringMaintenance("Rooftop work|DTSTART:20240312T060000Z RRULE:FREQ=MONTHLY;BYDAY=2TU|2h|Backbone\nBroken|RRULE:FREQ=YEARLY|1h")
+	if len(windows) != 1 {
+		t.Fatalf("Expected 1 window, got %d", len(windows))
+	}
+
+	// A week out, the next one is close enough to go on the page
+	now := time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC)
+	maintenance, ok := windows[0].nextOccurrence(now)
+	if !ok || !maintenance.Start.Equal(time.Date(2024, 4, 9, 6, 0, 0, 0, time.UTC)) || maintenance.End.Sub(maintenance.Start) != 2*time.Hour {
+		t.Errorf("Unexpected occurrence: %+v", maintenance)
+	}
+
+	// While it's going on it's still the next one
+	again, ok := windows[0].nextOccurrence(maintenance.Start.Add(time.Hour))
+	if !ok || again.ID != maintenance.ID {
+		t.Errorf("Expected the same occurrence while it's underway, got %+v", again)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin/render"
+	"github.com/robfig/cron/v3"
+)
+
+// How often to look for changes to the config file and the templates
+const reloadPollInterval = 2 * time.Second
+
+// Renders the page from templates that can be swapped out while we're
+// serving. gin's LoadHTMLGlob isn't safe to call once requests are coming in.
+type reloadableHTML struct {
+	pattern   string
+	templates atomic.Pointer[template.Template]
+}
+
+func (r *reloadableHTML) load() error {
+	templates, err := template.New("").ParseGlob(r.pattern)
+	if err != nil {
+		return err
+	}
+	r.templates.Store(templates)
+	return nil
+}
+
+func (r *reloadableHTML) Instance(name string, data any) render.Render {
+	return render.HTML{Template: r.templates.Load(), Name: name, Data: data}
+}
+
+// The reminder cron job, which moves when CSP_REMINDER_SCHEDULE does
+type reminderJob struct {
+	cron     *cron.Cron
+	csp      CSPService
+	schedule string
+	entry    cron.EntryID
+}
+
+// Puts the job on a new schedule. The old one keeps going if the new one is
+// no good.
+func (job *reminderJob) reschedule(schedule string) error {
+	if schedule == job.schedule {
+		return nil
+	}
+	entry, err := job.cron.AddFunc(schedule, func() {
+		err := job.csp.SendReminders(false)
+		if err != nil {
+			log.Printf("Cronjob returned error: %s\n", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if job.entry != 0 {
+		job.cron.Remove(job.entry)
+	}
+	job.entry, job.schedule = entry, schedule
+	return nil
+}
+
+// Keeps an eye on the config file and the templates, and picks up changes
+// without a restart, so the chat connections stay up.
+type configWatcher struct {
+	configPath string
+	html       *reloadableHTML
+	sources    []string
+
+	// Nil unless we're sending reminders
+	reminders *reminderJob
+
+	configModTime    time.Time
+	templatesModTime time.Time
+}
+
+func (watcher *configWatcher) run() {
+	watcher.configModTime = modTime(watcher.configPath)
+	watcher.templatesModTime = templatesModTime(watcher.html.pattern)
+	for range time.Tick(reloadPollInterval) {
+		if t := modTime(watcher.configPath); !t.Equal(watcher.configModTime) {
+			watcher.configModTime = t
+			watcher.reloadConfig()
+		}
+		if t := templatesModTime(watcher.html.pattern); !t.Equal(watcher.templatesModTime) {
+			watcher.templatesModTime = t
+			log.Println("Reloading templates...")
+			err := watcher.html.load()
+			if err != nil {
+				log.Printf("Could not reload templates, keeping the old ones. %s\n", err)
+			}
+		}
+	}
+}
+
+// Loads and checks the config again, and only swaps it in if it's good
+func (watcher *configWatcher) reloadConfig() {
+	log.Println("Reloading configuration...")
+	next, settings, err := loadConfig()
+	if err != nil {
+		log.Printf("Could not reload configuration, keeping the old one. %s\n", err)
+		return
+	}
+	problems := validateConfig(next, settings, watcher.sources, watcher.reminders != nil)
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			log.Println(problem)
+		}
+		log.Printf("Found %d problem(s) with the new configuration, keeping the old one.\n", len(problems))
+		return
+	}
+
+	if next.keepConnections(config()) {
+		log.Println("Sources, tokens, channels and the state file only change on a restart. Keeping the ones we started with.")
+	}
+	fileSettings = settings
+	liveConfig.Store(&next)
+
+	if watcher.reminders != nil {
+		err = watcher.reminders.reschedule(next.ReminderSchedule)
+		if err != nil {
+			log.Printf("Could not reschedule reminders. %s\n", err)
+		}
+	}
+	log.Println("Configuration reloaded.")
+}
+
+// We only read these when we connect, so a reload keeps the ones we started
+// with. Says whether the new config tried to change any of them.
+func (next *Config) keepConnections(current *Config) (changed bool) {
+	keep := func(value *string, old string) {
+		changed = changed || *value != old
+		*value = old
+	}
+	changed = !slices.Equal(next.Sources, current.Sources)
+	next.Sources = current.Sources
+
+	keep(&next.SlackLabel, current.SlackLabel)
+	keep(&next.SlackTeamID, current.SlackTeamID)
+	keep(&next.SlackAccessToken, current.SlackAccessToken)
+	keep(&next.SlackAppToken, current.SlackAppToken)
+	keep(&next.SlackStatusChannels, current.SlackStatusChannels)
+	keep(&next.SlackForwardChannelID, current.SlackForwardChannelID)
+	keep(&next.SlackTruncation, current.SlackTruncation)
+	keep(&next.SlackPublishers, current.SlackPublishers)
+	keep(&next.SlackPublisherGroups, current.SlackPublisherGroups)
+	keep(&next.SlackEscalationGroup, current.SlackEscalationGroup)
+
+	keep(&next.DiscordLabel, current.DiscordLabel)
+	keep(&next.DiscordToken, current.DiscordToken)
+	keep(&next.DiscordStatusChannelID, current.DiscordStatusChannelID)
+	keep(&next.DiscordForwardChannelID, current.DiscordForwardChannelID)
+	keep(&next.DiscordTruncation, current.DiscordTruncation)
+
+	keep(&next.MattermostLabel, current.MattermostLabel)
+	keep(&next.MattermostURL, current.MattermostURL)
+	keep(&next.MattermostToken, current.MattermostToken)
+	keep(&next.MattermostStatusChannelID, current.MattermostStatusChannelID)
+	keep(&next.MattermostTruncation, current.MattermostTruncation)
+
+	keep(&next.StateFile, current.StateFile)
+	return changed
+}
+
+// When a file last changed, or nothing if it isn't there
+func modTime(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// The last change to any of the templates. The directory changes when one is
+// added or removed.
+func templatesModTime(pattern string) time.Time {
+	latest := modTime(filepath.Dir(pattern))
+	paths, _ := filepath.Glob(pattern)
+	for _, path := range paths {
+		if t := modTime(path); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
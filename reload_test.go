@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestReloadConfig(t *testing.T) {
+	defer func(c Config, settings map[string]string) {
+		liveConfig.Store(&c)
+		fileSettings = settings
+	}(*config(), fileSettings)
+	unsetenv(t, "CSP_HELP_LINK", "CSP_SEVERITY_LEVELS", "CSP_DISCORD_TOKEN", "CSP_STATE_FILE", "CSP_TIMEZONE", "CSP_PIN_EXPIRY", "CSP_REMINDER_AFTER", "CSP_ESCALATE_AFTER", "CSP_UNPIN_AFTER_OK", "CSP_MAINTENANCE_REMINDER")
+	path := writeConfigFile(t, "config.yaml", "help_link: Old help\ndiscord_token: old-token\n")
+	t.Setenv("CSP_CONFIG_FILE", path)
+	c, settings, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileSettings = settings
+	liveConfig.Store(&c)
+
+	watcher := configWatcher{configPath: path, sources: []string{"file:updates.json"}}
+	err = os.WriteFile(path, []byte("help_link: New help\ndiscord_token: new-token\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.reloadConfig()
+	if config().HelpMessage != "New help" {
+		t.Errorf("Expected the help message to be reloaded, got %q", config().HelpMessage)
+	}
+	if config().DiscordToken != "old-token" {
+		t.Errorf("Expected the connection settings to stay put, got %q", config().DiscordToken)
+	}
+
+	// A bad config doesn't get swapped in
+	err = os.WriteFile(path, []byte("help_link: Broken help\nseverity_levels:\n  - {name: ok, label: OK, impact: ok, color: not a color}\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher.reloadConfig()
+	if config().HelpMessage != "New help" {
+		t.Errorf("Expected the old config to stay after a bad reload, got %q", config().HelpMessage)
+	}
+	if getenv("CSP_HELP_LINK") != "New help" {
+		t.Errorf("Expected the old file settings to stay after a bad reload, got %q", getenv("CSP_HELP_LINK"))
+	}
+}
+
+func TestReminderJobReschedule(t *testing.T) {
+	job := reminderJob{cron: cron.New()}
+	err := job.reschedule("0 17 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := job.entry
+
+	err = job.reschedule("not a schedule")
+	if err == nil || job.entry != first || job.schedule != "0 17 * * *" {
+		t.Error("Expected a bad schedule to leave the old one running")
+	}
+
+	err = job.reschedule("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := job.cron.Entries(); len(entries) != 1 || entries[0].ID != job.entry {
+		t.Errorf("Expected only the new schedule to be left, got %+v", entries)
+	}
+}
+
+func TestReloadableHTML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.html")
+	render := func(html *reloadableHTML) string {
+		w := httptest.NewRecorder()
+		err := html.Instance("index.html", "page").Render(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w.Body.String()
+	}
+
+	err := os.WriteFile(path, []byte("Old {{.}}"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := &reloadableHTML{pattern: filepath.Join(dir, "*")}
+	err = html.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := render(html); body != "Old page" {
+		t.Errorf("Unexpected render %q", body)
+	}
+
+	err = os.WriteFile(path, []byte("New {{.}}"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = html.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := render(html); body != "New page" {
+		t.Errorf("Expected the new template, got %q", body)
+	}
+
+	// A broken template leaves the last good one in place
+	err = os.WriteFile(path, []byte("Broken {{"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if html.load() == nil {
+		t.Error("Expected a broken template to fail to load")
+	}
+	if body := render(html); body != "New page" {
+		t.Errorf("Expected the last good template, got %q", body)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Where reminders about stale pins go. In the channel, everyone sees one
+// summary. As DMs, each author hears about their own, and the channel only
+// hears about the ones that have been ignored for a while.
+const (
+	ReminderChannel = "channel"
+	ReminderDM      = "dm"
+)
+
+func parseReminderMode(value string) string {
+	switch value {
+	case "", ReminderChannel:
+		return ReminderChannel
+	case ReminderDM:
+		return value
+	}
+	log.Printf("Unknown reminder mode '%s', reminding the channel.\n", value)
+	return ReminderChannel
+}
+
+// How long things stay pinned before a reminder if CSP_REMINDER_AFTER doesn't
+// say, like before it could be set
+const defaultReminderAfter = 24 * time.Hour
+
+// Whether an update has been pinned long enough to remind someone about it.
+// Sending reminders now skips the wait.
+func reminderDue(severity string, pinnedFor time.Duration, now bool) bool {
+	after := severityDuration(config().ReminderAfter, severity)
+	if after <= 0 {
+		after = defaultReminderAfter
+	}
+	return now || pinnedFor >= after
+}
+
+// Whether an update has been pinned so long that its author reminding
+// themselves isn't enough anymore
+func escalationDue(severity string, pinnedFor time.Duration) bool {
+	after := severityDuration(config().EscalateAfter, severity)
+	return after > 0 && pinnedFor >= after
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReminderThresholds(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.ReminderAfter = parseSeverityDurations("CSP_REMINDER_AFTER", "error=4h, 24h", true)
+		c.EscalateAfter = parseSeverityDurations("CSP_ESCALATE_AFTER", "error=12h", true)
+	})
+
+	for _, test := range []struct {
+		severity  string
+		pinnedFor time.Duration
+		due       bool
+		escalate  bool
+	}{
+		{SeverityError, 2 * time.Hour, false, false},
+		{SeverityError, 5 * time.Hour, true, false},
+		{SeverityError, 13 * time.Hour, true, true},
+		{SeverityWarn, 5 * time.Hour, false, false},
+		{SeverityWarn, 100 * time.Hour, true, false},
+		{"", 25 * time.Hour, true, false},
+	} {
+		if due := reminderDue(test.severity, test.pinnedFor, false); due != test.due {
+			t.Errorf("Expected reminderDue(%q, %s) to be %v", test.severity, test.pinnedFor, test.due)
+		}
+		if escalate := escalationDue(test.severity, test.pinnedFor); escalate != test.escalate {
+			t.Errorf("Expected escalationDue(%q, %s) to be %v", test.severity, test.pinnedFor, test.escalate)
+		}
+	}
+	if !reminderDue(SeverityWarn, time.Minute, true) {
+		t.Error("Sending reminders now should skip the wait")
+	}
+}
+
+func TestReminderWithoutDefault(t *testing.T) {
+	withConfig(t, func(c *Config) { c.ReminderAfter = parseSeverityDurations("CSP_REMINDER_AFTER", "error=4h", true) })
+	if reminderDue(SeverityWarn, time.Hour, false) {
+		t.Error("Expected severities that aren't listed to wait the usual day")
+	}
+	if !reminderDue(SeverityWarn, 25*time.Hour, false) {
+		t.Error("Expected severities that aren't listed to be reminded after a day")
+	}
+}
@@ -1,19 +1,70 @@
 package main
 
 import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
 type CSPService interface {
 	BuildStatusPage() error
 	StatusPage(gin *gin.Context)
+	Page() *CSPPage
 	SendReminders(now bool) error
+	Announce(message string) error
+	Unpin(updateID string, note string) error
 	Run()
 }
 
 type ReminderInfo struct {
 	userID string
 	link   string
-	ts     string
+	at     time.Time
 	status string
+	// The update's ID, for the buttons on the reminder
+	updateID string
+}
+
+// Sets up the backend described by one entry of CSP_SOURCES. Entries look
+// like "slack", "slack:<name>", "discord", "mattermost" or "file:<path>".
+func NewCSPService(source string) (CSPService, error) {
+	kind, arg, _ := strings.Cut(source, ":")
+	switch kind {
+	case "slack":
+		workspace := defaultSlackWorkspace()
+		if arg != "" {
+			workspace = slackWorkspaceFromEnv(arg)
+		}
+		log.Println("Connecting to Slack...")
+		cspSlack, err := NewCSPSlack(workspace)
+		if err != nil {
+			return nil, fmt.Errorf("could not set up new CSPSlack service: %w", err)
+		}
+		return &cspSlack, nil
+	case "discord":
+		log.Println("Connecting to Discord...")
+		cspDiscord, err := NewCSPDiscord()
+		if err != nil {
+			return nil, fmt.Errorf("could not set up new CSPDiscord service: %w", err)
+		}
+		return &cspDiscord, nil
+	case "mattermost":
+		log.Println("Connecting to Mattermost...")
+		cspMattermost, err := NewCSPMattermost()
+		if err != nil {
+			return nil, fmt.Errorf("could not set up new CSPMattermost service: %w", err)
+		}
+		return &cspMattermost, nil
+	case "file":
+		log.Printf("Reading updates from %s...\n", arg)
+		cspFile, err := NewCSPFile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("could not set up new CSPFile service: %w", err)
+		}
+		return &cspFile, nil
+	}
+	return nil, fmt.Errorf("unknown source '%s'", source)
 }
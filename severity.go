@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// How much trouble a severity level means. Every level is one of these, and
+// it's what decides how the level looks on the page and what it does to
+// uptime. The default levels are named after them too.
+const (
+	SeverityOK    = "ok"
+	SeverityWarn  = "warn"
+	SeverityError = "error"
+)
+
+// One of the levels an update can be marked with, least severe first. Each
+// backend maps its own reactions onto these.
+type SeverityLevel struct {
+	Name         string
+	Label        string
+	Impact       string
+	Emoji        string
+	DiscordEmoji string
+	Color        string
+	Icon         string
+	Rank         int
+}
+
+// The levels we've always had, with the emoji from CSP_CARD_*_EMOJI and
+// CSP_DISCORD_*_EMOJI
+func defaultSeverityLevels(settings configSettings) []SeverityLevel {
+	return rankSeverityLevels([]SeverityLevel{
+		{
+			Name:         SeverityOK,
+			Label:        "OK/Info",
+			Impact:       SeverityOK,
+			Emoji:        settings.get("CSP_CARD_OK_EMOJI"),
+			DiscordEmoji: settings.getDefault("CSP_DISCORD_OK_EMOJI", "✅"),
+			Color:        settings.get("CSP_CARD_OK_COLOR"),
+			Icon:         impactIcon(SeverityOK),
+		},
+		{
+			Name:         SeverityWarn,
+			Label:        "Warning",
+			Impact:       SeverityWarn,
+			Emoji:        settings.get("CSP_CARD_WARN_EMOJI"),
+			DiscordEmoji: settings.getDefault("CSP_DISCORD_WARN_EMOJI", "⚠️"),
+			Color:        settings.get("CSP_CARD_WARN_COLOR"),
+			Icon:         impactIcon(SeverityWarn),
+		},
+		{
+			Name:         SeverityError,
+			Label:        "Critical",
+			Impact:       SeverityError,
+			Emoji:        settings.get("CSP_CARD_ERROR_EMOJI"),
+			DiscordEmoji: settings.getDefault("CSP_DISCORD_ERROR_EMOJI", "🔥"),
+			Color:        settings.get("CSP_CARD_ERROR_COLOR"),
+			Icon:         impactIcon(SeverityError),
+		},
+	})
+}
+
+// Custom levels are configured one per line, least severe first, as
+// "name|Label|impact|emoji|Discord emoji|color|icon". The impact is ok, warn
+// or error. Everything after it is optional, e.g.
+//
+//	info|Info|ok|information_source|ℹ️
+//	degraded|Degraded performance|warn|warning|⚠️|#fff3cd
+//	major_outage|Major outage|error|fire|🔥||error.svg
+func parseSeverityLevels(value string) []SeverityLevel {
+	levels, problems := readSeverityLevels(value)
+	for _, problem := range problems {
+		log.Printf("%s. Skipping it.\n", problem)
+	}
+	return levels
+}
+
+// Does the work for parseSeverityLevels, and says what was wrong with any
+// lines it had to skip
+func readSeverityLevels(value string) (levels []SeverityLevel, problems []string) {
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if len(fields) < 3 || fields[0] == "" {
+			problems = append(problems, fmt.Sprintf("Severity level '%s' needs a name, a label and an impact", line))
+			continue
+		}
+		level := SeverityLevel{Name: fields[0], Label: fields[1], Impact: fields[2]}
+		switch level.Impact {
+		case SeverityOK, SeverityWarn, SeverityError:
+		default:
+			problems = append(problems, fmt.Sprintf("Severity level '%s' has unknown impact '%s'. Use ok, warn or error", level.Name, level.Impact))
+			continue
+		}
+		if seen[level.Name] {
+			problems = append(problems, fmt.Sprintf("Severity level '%s' is listed twice", level.Name))
+			continue
+		}
+		seen[level.Name] = true
+		if len(fields) > 3 {
+			level.Emoji = strings.Trim(fields[3], ":")
+		}
+		if len(fields) > 4 {
+			level.DiscordEmoji = fields[4]
+		}
+		if len(fields) > 5 {
+			level.Color = fields[5]
+		}
+		if len(fields) > 6 {
+			level.Icon = fields[6]
+		}
+		if level.Label == "" {
+			level.Label = level.Name
+		}
+		if level.Icon == "" {
+			level.Icon = impactIcon(level.Impact)
+		}
+		levels = append(levels, level)
+	}
+	return rankSeverityLevels(levels), problems
+}
+
+// Later levels are worse. Ranks start at 1, so anything unknown ranks below
+// everything.
+func rankSeverityLevels(levels []SeverityLevel) []SeverityLevel {
+	for i := range levels {
+		levels[i].Rank = i + 1
+	}
+	return levels
+}
+
+func impactIcon(impact string) string {
+	switch impact {
+	case SeverityWarn:
+		return "warning.svg"
+	case SeverityError:
+		return "error.svg"
+	}
+	return "checkmark.svg"
+}
+
+func severityLevel(severity string) (SeverityLevel, bool) {
+	for _, level := range config().SeverityLevels {
+		if level.Name == severity {
+			return level, true
+		}
+	}
+	return SeverityLevel{}, false
+}
+
+// How much trouble a severity means, or nothing if it isn't one
+func severityImpact(severity string) string {
+	level, _ := severityLevel(severity)
+	return level.Impact
+}
+
+// Ranks how bad each severity is, so we can find the worst one
+func severityRank(severity string) int {
+	level, _ := severityLevel(severity)
+	return level.Rank
+}
+
+// The level that means everything's fine again, which is the first one that
+// doesn't mean trouble
+func resolvedSeverity() string {
+	return severityWithImpact(SeverityOK)
+}
+
+// The least severe level with the given impact
+func severityWithImpact(impact string) string {
+	for _, level := range config().SeverityLevels {
+		if level.Impact == impact {
+			return level.Name
+		}
+	}
+	return ""
+}
+
+// Maps one of the configured emoji onto a severity
+func emojiSeverity(emoji string) string {
+	for _, level := range config().SeverityLevels {
+		if level.Emoji != "" && level.Emoji == emoji {
+			return level.Name
+		}
+	}
+	return ""
+}
+
+// The configured emoji for a severity, the other way around
+func severityEmoji(severity string) string {
+	level, _ := severityLevel(severity)
+	return level.Emoji
+}
+
+// Every configured emoji, for clearing them all off a message
+func severityEmojis() (emojis []string) {
+	for _, level := range config().SeverityLevels {
+		if level.Emoji != "" {
+			emojis = append(emojis, level.Emoji)
+		}
+	}
+	return emojis
+}
+
+// Compares a string you give it to a string passed in the config
+func isRelevantReaction(reaction string) bool {
+	return emojiSeverity(reaction) != ""
+}
+
+// Maps a Discord emoji onto one of our severities
+func discordEmojiSeverity(emoji string) string {
+	for _, level := range config().SeverityLevels {
+		if level.DiscordEmoji != "" && level.DiscordEmoji == emoji {
+			return level.Name
+		}
+	}
+	return ""
+}
+
+func discordSeverityEmoji(severity string) string {
+	level, _ := severityLevel(severity)
+	return level.DiscordEmoji
+}
+
+func discordSeverityEmojis() (emojis []string) {
+	for _, level := range config().SeverityLevels {
+		if level.DiscordEmoji != "" {
+			emojis = append(emojis, level.DiscordEmoji)
+		}
+	}
+	return emojis
+}
+
+// The levels worst first, the way the buttons list them
+func severityLevelsWorstFirst() (levels []SeverityLevel) {
+	c := config()
+	for i := len(c.SeverityLevels) - 1; i >= 0; i-- {
+		levels = append(levels, c.SeverityLevels[i])
+	}
+	return levels
+}
+
+// What a level's buttons say, like ":fire: Critical". Slack turns the
+// shortcode into the emoji.
+func (level SeverityLevel) ButtonLabel() string {
+	if level.Emoji == "" {
+		return level.Label
+	}
+	return ":" + level.Emoji + ": " + level.Label
+}
+
+func (level SeverityLevel) DiscordButtonLabel() string {
+	if level.DiscordEmoji == "" {
+		return level.Label
+	}
+	return level.DiscordEmoji + " " + level.Label
+}
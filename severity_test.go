@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestParseSeverityLevels(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.SeverityLevels = parseSeverityLevels(`
+			info|Info|ok|:information_source:|ℹ️
+			maintenance|Maintenance|warn|hammer_and_wrench
+			degraded|Degraded performance|warn|warning|⚠️|#fff3cd
+			partial_outage|Partial outage|error|large_orange_circle
+			major_outage|Major outage|error|fire|🔥||major.svg
+			broken|Broken|terrible|x
+			info|Info again|ok|x
+		`)
+	})
+
+	if len(config().SeverityLevels) != 5 {
+		t.Fatalf("Expected 5 levels, got %+v", config().SeverityLevels)
+	}
+	if severityRank("major_outage") <= severityRank("partial_outage") || severityRank("info") <= severityRank("") {
+		t.Errorf("Expected later levels to rank higher: %+v", config().SeverityLevels)
+	}
+	if severity := emojiSeverity("information_source"); severity != "info" {
+		t.Errorf("Expected the colons to be trimmed off the emoji, got %q", severity)
+	}
+	if severity := discordEmojiSeverity("🔥"); severity != "major_outage" {
+		t.Errorf("Expected 🔥 to map onto major_outage, got %q", severity)
+	}
+	if resolved := resolvedSeverity(); resolved != "info" {
+		t.Errorf("Expected resolving to set info, got %q", resolved)
+	}
+	if maintenance := maintenanceSeverity(); maintenance != "maintenance" {
+		t.Errorf("Expected maintenance to get its own level, got %q", maintenance)
+	}
+
+	var update StatusUpdate
+	update.setSeverity("degraded")
+	if update.BackgroundClass != "list-group-item-warning" || update.IconFilename != "warning.svg" || update.Color != "#fff3cd" {
+		t.Errorf("Unexpected card for a degraded update: %+v", update)
+	}
+	update.setSeverity("major_outage")
+	if update.IconFilename != "major.svg" || update.SeverityLabel != "Major outage" {
+		t.Errorf("Unexpected card for a major outage: %+v", update)
+	}
+}
@@ -3,11 +3,14 @@ package main
 import (
 	"errors"
 	"fmt"
+	"html/template"
 	"log"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,29 +21,190 @@ import (
 const (
 	// Callback ID
 	CSPUpdateStatusPage = "csp_update_status_page"
-	CSPSetOK            = "csp_set_ok"
-	CSPSetWarn          = "csp_set_warn"
-	CSPSetError         = "csp_set_errteamID"
 	CSPCancel           = "csp_cancel"
 
+	// Severity buttons are this followed by the level's name
+	CSPSetSeverityPrefix = "csp_set_severity_"
+
 	CSPPin = "pin"
 
 	CSPForward = "forward"
+
+	// Followed by the incident state, e.g. "csp_state_monitoring"
+	CSPSetStatePrefix = "csp_state_"
+
+	CSPTagComponents   = "csp_tag_components"
+	CSPComponentsModal = "csp_components_modal"
+	CSPComponentsBlock = "components"
+
+	// Writing an update from scratch, with /status or the global shortcut
+	CSPComposeCommand  = "/status"
+	CSPComposeShortcut = "csp_compose"
+	CSPComposeModal    = "csp_compose_modal"
+
+	// Blocks in the compose modal. Each has a single element with the same
+	// action ID.
+	CSPComposeMessage    = "message"
+	CSPComposeChannel    = "channel"
+	CSPComposeSeverity   = "severity"
+	CSPComposeOptions    = "compose_options"
+	CSPComposeResolution = "resolution"
+	CSPComposeVisibility = "visibility"
+
+	// Copying a message from anywhere onto the page
+	CSPPublishShortcut = "csp_publish"
+	CSPPublishModal    = "csp_publish_modal"
+
+	// Signing off on someone else's update. The value is the update ID.
+	CSPApprove = "csp_approve"
+
+	// The preview posted under a new update. Values are the update ID.
+	CSPPublishDraft = "csp_publish_draft"
+	CSPPreviewLink  = "csp_preview_link"
+
+	// Buttons in the App Home. Their value is the update ID.
+	CSPHomeSetSeverityPrefix = "csp_home_set_severity_"
+	CSPHomeResolve           = "csp_home_resolve"
+	CSPHomeUnpin             = "csp_home_unpin"
+	CSPHomeFollowUp          = "csp_home_follow_up"
+	CSPFollowUpModal         = "csp_follow_up_modal"
+
+	// When a pinned update should come down on its own
+	CSPPinExpiry = "csp_pin_expiry"
+
+	// Buttons on the reminder message
+	CSPReminderUnpin   = "csp_reminder_unpin"
+	CSPReminderResolve = "csp_reminder_resolve"
+	CSPReminderSnooze  = "csp_reminder_snooze"
+
+	// Scheduling maintenance
+	CSPMaintenanceShortcut    = "csp_maintenance"
+	CSPMaintenanceModal       = "csp_maintenance_modal"
+	CSPMaintenanceTitle       = "maintenance_title"
+	CSPMaintenanceDescription = "maintenance_description"
+	CSPMaintenanceStart       = "maintenance_start"
+	CSPMaintenanceEnd         = "maintenance_end"
+	CSPMaintenanceCancel      = "csp_maintenance_cancel"
+
+	CSPVisibilityPublic   = "public"
+	CSPVisibilityInternal = "internal"
+	CSPVisibilityDraft    = "draft"
 )
 
+// A channel we take status updates from, and the part of the page its
+// updates belong to.
+type StatusChannel struct {
+	ID        string
+	Component string
+}
+
+// Status channels are configured as a comma separated list of channel IDs,
+// each optionally followed by the component it covers, e.g.
+// "C0123:Backbone,C0456:Hubs,C0789".
+func parseStatusChannels(value string) (channels []StatusChannel) {
+	for _, entry := range strings.Split(value, ",") {
+		id, component, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if id == "" {
+			continue
+		}
+		channels = append(channels, StatusChannel{ID: id, Component: strings.TrimSpace(component)})
+	}
+	return channels
+}
+
+// Everything we need to know to talk to one Slack workspace. The page can be
+// fed from more than one of them at once.
+type SlackWorkspace struct {
+	Label            string
+	TeamID           string
+	AccessToken      string
+	AppToken         string
+	StatusChannels   []StatusChannel
+	ForwardChannelID string
+	BotID            string
+	Truncation       string
+
+	// Who's allowed to put things on the page, by user ID or user group ID.
+	// If both are empty, anyone in the status channels can.
+	Publishers      []string
+	PublisherGroups []string
+
+	// The user group to ping about pins that have been ignored for too long
+	EscalationGroup string
+}
+
+// The workspace configured by the plain CSP_SLACK_* variables
+func defaultSlackWorkspace() SlackWorkspace {
+	c := config()
+	return SlackWorkspace{
+		Label:            c.SlackLabel,
+		TeamID:           c.SlackTeamID,
+		AccessToken:      c.SlackAccessToken,
+		AppToken:         c.SlackAppToken,
+		StatusChannels:   parseStatusChannels(c.SlackStatusChannels),
+		ForwardChannelID: c.SlackForwardChannelID,
+		Truncation:       c.SlackTruncation,
+		Publishers:       splitList(c.SlackPublishers),
+		PublisherGroups:  splitList(c.SlackPublisherGroups),
+		EscalationGroup:  c.SlackEscalationGroup,
+	}
+}
+
+// Additional workspaces are configured with CSP_SLACK_<NAME>_* variables
+func slackWorkspaceFromEnv(name string) SlackWorkspace {
+	prefix := "CSP_SLACK_" + strings.ToUpper(name) + "_"
+	return SlackWorkspace{
+		Label:            getenv(prefix + "LABEL"),
+		TeamID:           getenv(prefix + "TEAMID"),
+		AccessToken:      getenv(prefix + "ACCESS_TOKEN"),
+		AppToken:         getenv(prefix + "APP_TOKEN"),
+		StatusChannels:   parseStatusChannels(getenv(prefix + "STATUS_CHANNEL")),
+		ForwardChannelID: getenv(prefix + "FORWARD_CHANNEL"),
+		Truncation:       getenv(prefix + "TRUNCATION"),
+		Publishers:       splitList(getenv(prefix + "PUBLISHERS")),
+		PublisherGroups:  splitList(getenv(prefix + "PUBLISHER_GROUPS")),
+		EscalationGroup:  getenv(prefix + "ESCALATION_GROUP"),
+	}
+}
+
+// Who's in the publisher groups, as of the last time we asked. Reminders run
+// on their own goroutine, so this needs a lock.
+type publisherGroupCache struct {
+	mu      sync.Mutex
+	members map[string]bool
+	fetched time.Time
+}
+
 type CSPSlack struct {
+	workspace SlackWorkspace
+
 	slackAPI    *slack.Client
 	slackSocket *socketmode.Client
 
-	channelHistory []slack.Message
+	// Keyed by status channel ID
+	channelHistory map[string][]slack.Message
 
 	shouldUpdate bool
 
-	page CSPPage
+	// Everyone who's looked at the App Home, so we can keep it up to date
+	homeUsers map[string]bool
+
+	publisherGroups *publisherGroupCache
+
+	page *CSPPage
 }
 
-func NewCSPSlack() (app CSPSlack, err error) {
-	app.slackAPI = slack.New(config.SlackAccessToken, slack.OptionAppLevelToken(config.SlackAppToken))
+func NewCSPSlack(workspace SlackWorkspace) (app CSPSlack, err error) {
+	app.page = &CSPPage{}
+	app.workspace = workspace
+	app.homeUsers = make(map[string]bool)
+	app.publisherGroups = &publisherGroupCache{}
+	for _, channel := range workspace.StatusChannels {
+		if channel.Component != "" {
+			app.page.componentOrder = append(app.page.componentOrder, channel.Component)
+		}
+	}
+	app.slackAPI = slack.New(app.workspace.AccessToken, slack.OptionAppLevelToken(app.workspace.AppToken))
 	app.slackSocket = socketmode.New(app.slackAPI,
 		socketmode.OptionLog(log.New(os.Stdout, "socketmode: ", log.Lshortfile|log.LstdFlags)),
 	)
@@ -53,7 +217,13 @@ func NewCSPSlack() (app CSPSlack, err error) {
 
 	// Get some deets we'll need from the slack API
 	authTestResponse, err := app.slackAPI.AuthTest()
-	config.SlackBotID = authTestResponse.UserID
+	if err != nil {
+		return app, err
+	}
+	app.workspace.BotID = authTestResponse.UserID
+	if app.workspace.Label == "" {
+		app.workspace.Label = authTestResponse.Team
+	}
 
 	// Initialize the actual data we need for the status page
 	err = app.BuildStatusPage()
@@ -66,66 +236,308 @@ func NewCSPSlack() (app CSPSlack, err error) {
 // Nuke the old slices and re-build them
 func (app *CSPSlack) BuildStatusPage() (err error) {
 	log.Println("Building Status Page...")
-	app.page.updates = make([]StatusUpdate, 0)
-	app.page.pinnedUpdates = make([]StatusUpdate, 0)
-	for _, message := range app.channelHistory {
-		// Ignore messages that don't mention us. Also, ignore messages that
-		// mention us but are empty!
-		if !botActionablyMentioned(message.Text) {
+	updates := make([]StatusUpdate, 0)
+	pinnedUpdates := make([]StatusUpdate, 0)
+	previews := make(map[string]UpdatePreview)
+	for _, channel := range app.workspace.StatusChannels {
+		for _, message := range app.channelHistory[channel.ID] {
+			if !app.isStatusUpdate(channel.ID, message) {
+				continue
+			}
+			updateID := slackUpdateID(channel.ID, message.Timestamp)
+			author := message.User
+			if composed, ok := app.composedUpdate(channel.ID, message); ok {
+				if composed.Internal {
+					continue
+				}
+				author = composed.Author
+			}
+
+			// Anything that needs approving and was never asked about gets
+			// asked about now
+			severity := emojiSeverity(GetPinnedMessageStatus(message.Reactions, app.workspace.BotID))
+			pending := awaitingApproval(updateID, severity, slackTSToTime(message.Timestamp))
+			if pending {
+				app.requestApproval(channel.ID, message.Timestamp, author, severity)
+			}
+
+			// Drafts and updates waiting on approval stay off the page, but
+			// can still be previewed
+			hidden := store.isDraft(updateID) || pending
+			token, hasPreview := store.previewToken(updateID)
+			if hidden && !hasPreview {
+				continue
+			}
+
+			update, err := app.buildStatusUpdate(channel, message)
+			if err != nil {
+				return err
+			}
+
+			pinned := len(message.PinnedTo) > 0
+			// Follow-ups only matter while the update is still current
+			if pinned && message.ReplyCount > 0 {
+				update.Replies, err = app.buildStatusReplies(channel.ID, message.Timestamp)
+				if err != nil {
+					log.Printf("Could not get replies to %s: %s\n", update.ID, err)
+				}
+			}
+
+			if hasPreview {
+				previews[token] = UpdatePreview{Update: update, Pinned: pinned, Live: !hidden}
+			}
+			if hidden {
+				continue
+			}
+			if pinned {
+				pinnedUpdates = append(pinnedUpdates, update)
+			} else {
+				updates = append(updates, update)
+			}
+		}
+	}
+
+	// Interleave the channels again
+	sortNewestFirst(pinnedUpdates)
+	sortNewestFirst(updates)
+
+	recordUnpinned(app.page.setUpdates(pinnedUpdates, updates), time.Now())
+	app.page.setPreviews(previews)
+	return nil
+}
+
+func (app *CSPSlack) buildStatusUpdate(channel StatusChannel, message slack.Message) (update StatusUpdate, err error) {
+	update.ID = slackUpdateID(channel.ID, message.Timestamp)
+
+	// We posted composed updates ourselves, but someone else wrote them
+	author := message.User
+	if composed, ok := app.composedUpdate(channel.ID, message); ok {
+		author = composed.Author
+		update.ExpectedResolution = composed.ExpectedResolution
+	}
+
+	realName := app.authorName(author)
+
+	update.HTML, err = app.renderMessageText(message.Text)
+	if err != nil {
+		return update, err
+	}
+
+	update.SentBy = realName
+	update.Origin = app.workspace.Label
+	update.Component = channel.Component
+	update.tagComponents(channel.Component)
+	update.tagComponents(componentsFromHashtags(message.Text)...)
+	update.tagComponents(store.updateComponents(update.ID)...)
+	update.setIncidentStates(store.incidentStates(update.ID))
+	update.Time = slackTSToTime(message.Timestamp)
+	update.BackgroundClass = ""
+	update.IconFilename = ""
+
+	for _, reaction := range message.Reactions {
+		// Only take action on our reactions
+		if botReaction := stringInSlice(reaction.Users, app.workspace.BotID); !botReaction {
 			continue
 		}
 
-		msgUser, err := app.slackSocket.GetUserInfo(message.User)
-		if err != nil {
-			log.Println(err)
-			return err
+		// Use the first reaction sent by the bot that we find
+		if severity := emojiSeverity(reaction.Name); severity != "" {
+			update.setSeverity(severity)
 		}
-		realName := msgUser.RealName
-		var update StatusUpdate
 
-		// Disgusting dependency chain to parse Mrkdwn to HTML
-		botID := fmt.Sprintf("<@%s>", config.SlackBotID)
-		noBots := strings.Replace(message.Text, botID, "", -1)
-		humanifiedChannels, err := app.slackChannelLinksToMarkdown(noBots)
+	}
+	return update, nil
+}
+
+// What to show for whoever wrote something. One author we can't look up
+// shouldn't take the whole page down with it.
+func (app *CSPSlack) authorName(userID string) string {
+	user, err := app.slackSocket.GetUserInfo(userID)
+	if err != nil {
+		log.Printf("Could not look up author '%s': %s\n", userID, err)
+		return "Unknown"
+	}
+	return user.RealName
+}
+
+// Disgusting dependency chain to parse Mrkdwn to HTML. Previews have to come
+// out exactly like the page does, so everything goes through here.
+func (app *CSPSlack) renderMessageText(text string) (template.HTML, error) {
+	botID := fmt.Sprintf("<@%s>", app.workspace.BotID)
+	noBots := strings.Replace(text, botID, "", -1)
+	humanifiedChannels, err := app.slackChannelLinksToMarkdown(noBots)
+	if err != nil {
+		return "", err
+	}
+	return MrkdwnToHTML(humanifiedChannels), nil
+}
+
+// Replies in the thread that mention us are follow-ups to the update, and go
+// underneath it oldest first. Everything else in there is just chatter.
+func (app *CSPSlack) buildStatusReplies(channelID string, threadTs string) (replies []StatusReply, err error) {
+	conversation, err := app.getThreadConversation(channelID, threadTs)
+	if err != nil {
+		return nil, err
+	}
+	botID := fmt.Sprintf("<@%s>", app.workspace.BotID)
+	for _, message := range conversation {
+		if message.Timestamp == threadTs || !app.isStatusUpdate(channelID, message) {
+			continue
+		}
+
+		// Follow-ups posted from the App Home are ours, but somebody else
+		// wrote them
+		author := message.User
+		if composed, ok := app.composedUpdate(channelID, message); ok {
+			author = composed.Author
+		}
+		html, err := app.renderMessageText(message.Text)
 		if err != nil {
-			return err
+			return replies, err
 		}
-		update.HTML = MrkdwnToHTML(humanifiedChannels)
 
-		update.SentBy = realName
-		update.TimeStamp = slackTSToHumanTime(message.Timestamp)
-		update.BackgroundClass = ""
-		update.IconFilename = ""
+		reply := StatusReply{
+			HTML:   html,
+			SentBy: app.authorName(author),
+			Time:   slackTSToTime(message.Timestamp),
+		}
+		reply.State, _ = parseIncidentState(strings.Replace(message.Text, botID, "", -1))
+		replies = append(replies, reply)
+	}
+	sort.SliceStable(replies, func(i, j int) bool {
+		return replies[i].Time.Before(replies[j].Time)
+	})
+	return replies, nil
+}
+
+// Publishes the App Home for everyone who's opened it so far
+func (app *CSPSlack) refreshHomes() {
+	if len(app.homeUsers) == 0 {
+		return
+	}
+	updates := app.homeUpdates()
+	for userID := range app.homeUsers {
+		_, err := app.slackAPI.PublishView(userID, CreateHomeView(updates, upcomingMaintenance(), app.canPublish(userID)), "")
+		if err != nil {
+			log.Printf("Could not publish App Home for %s: %s\n", userID, err)
+		}
+	}
+}
 
-		for _, reaction := range message.Reactions {
-			// Only take action on our reactions
-			if botReaction := stringInSlice(reaction.Users, config.SlackBotID); !botReaction {
+func (app *CSPSlack) homeUpdates() (updates []homeUpdate) {
+	botID := fmt.Sprintf("<@%s>", app.workspace.BotID)
+	for _, channel := range app.workspace.StatusChannels {
+		for _, message := range app.channelHistory[channel.ID] {
+			if len(message.PinnedTo) == 0 || !app.isStatusUpdate(channel.ID, message) {
 				continue
 			}
-
-			// Use the first reaction sent by the bot that we find
-			switch reaction.Name {
-			case config.StatusOKEmoji:
-				update.BackgroundClass = "list-group-item-success"
-				update.IconFilename = "checkmark.svg"
-			case config.StatusWarnEmoji:
-				update.BackgroundClass = "list-group-item-warning"
-				update.IconFilename = "warning.svg"
-			case config.StatusErrorEmoji:
-				update.BackgroundClass = "list-group-item-danger"
-				update.IconFilename = "error.svg"
+			update := homeUpdate{
+				ID:    slackUpdateID(channel.ID, message.Timestamp),
+				Text:  strings.TrimSpace(strings.Replace(message.Text, botID, "", -1)),
+				Emoji: GetPinnedMessageStatus(message.Reactions, app.workspace.BotID),
+				Age:   humanDuration(time.Since(slackTSToTime(message.Timestamp))),
 			}
-
-		}
-		if len(message.PinnedTo) > 0 {
-			app.page.pinnedUpdates = append(app.page.pinnedUpdates, update)
-		} else {
-			app.page.updates = append(app.page.updates, update)
+			update.Pending = awaitingApproval(update.ID, emojiSeverity(update.Emoji), slackTSToTime(message.Timestamp))
+			update.Draft = store.isDraft(update.ID)
+			expiry := StatusUpdate{ID: update.ID, Severity: emojiSeverity(update.Emoji), Time: slackTSToTime(message.Timestamp)}
+			if at, ok := pinExpiry(expiry); ok {
+				update.Expires = humanDuration(time.Until(at))
+			}
+			if states := store.incidentStates(update.ID); len(states) > 0 {
+				update.State = states[len(states)-1].State
+			}
+			permalink, err := app.slackAPI.GetPermalink(&slack.PermalinkParameters{
+				Channel: channel.ID,
+				Ts:      message.Timestamp,
+			})
+			if err == nil {
+				update.Permalink = permalink
+			}
+			updates = append(updates, update)
 		}
+	}
 
+	// Leave room in the view for everything else
+	if len(updates) > 20 {
+		updates = updates[:20]
 	}
+	return updates
+}
 
-	return nil
+// Holds an update back from the page until someone else approves it, if it
+// needs that. Asking again for something that's already been asked about
+// does nothing.
+func (app *CSPSlack) requestApproval(channelID string, timestamp string, requester string, severity string) {
+	if !approvalRequired(severity) {
+		return
+	}
+	updateID := slackUpdateID(channelID, timestamp)
+	if _, ok := store.approval(updateID); ok {
+		return
+	}
+
+	approval := Approval{RequestedBy: requester, RequestedAt: time.Now()}
+	_, messageTs, err := app.slackSocket.PostMessage(
+		channelID,
+		slack.MsgOptionTS(timestamp),
+		slack.MsgOptionBlocks(CreateApprovalRequestMsg(updateID, requester)...),
+	)
+	if err != nil {
+		// It stays off the page regardless, and the next rebuild asks again
+		log.Printf("Could not ask for approval for %s: %s\n", updateID, err)
+		return
+	}
+	approval.MessageID = messageTs
+	err = store.setApproval(updateID, approval)
+	if err != nil {
+		log.Println(err)
+	}
+	log.Printf("%s is waiting on approval\n", updateID)
+}
+
+// Posts how an update is going to look on the page in its thread, so that
+// formatting surprises get caught before anyone else sees them
+func (app *CSPSlack) postPreview(channelID string, timestamp string) {
+	blocks, err := app.previewMsg(channelID, timestamp)
+	if err != nil {
+		log.Printf("Could not build preview: %s\n", err)
+		return
+	}
+	_, _, err = app.slackSocket.PostMessage(channelID, slack.MsgOptionTS(timestamp), slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		log.Printf("Could not post preview: %s\n", err)
+	}
+}
+
+func (app *CSPSlack) previewMsg(channelID string, timestamp string) ([]slack.Block, error) {
+	message, err := app.getMessage(channelID, timestamp, "")
+	if err != nil {
+		return nil, err
+	}
+	html, err := app.renderMessageText(message.Text)
+	if err != nil {
+		return nil, err
+	}
+	updateID := slackUpdateID(channelID, timestamp)
+	token, err := store.createPreviewToken(updateID)
+	if err != nil {
+		log.Println(err)
+	}
+	draft, isDraft := store.draft(updateID)
+	return CreatePreviewMsg(updateID, html, previewURL(token), draft, isDraft), nil
+}
+
+// Swaps the severity reaction on an update for a new one
+func (app *CSPSlack) setUpdateSeverity(channelID string, timestamp string, emoji string) error {
+	err := app.clearReactions(
+		channelID,
+		timestamp,
+		severityEmojis(),
+	)
+	if err != nil {
+		return err
+	}
+	return app.slackSocket.AddReaction(emoji, slack.NewRefToMessage(channelID, timestamp))
 }
 
 // Pass-Thru the interface to the Page object
@@ -133,70 +545,149 @@ func (app *CSPSlack) StatusPage(gin *gin.Context) {
 	app.page.statusPage(gin)
 }
 
+func (app *CSPSlack) Page() *CSPPage {
+	return app.page
+}
+
+// Each status channel gets reminded about its own pins
 func (app *CSPSlack) SendReminders(now bool) error {
 	fmt.Println("Sending unpin reminders...")
-	var pinnedMessageLinks []ReminderInfo
-	for _, message := range app.channelHistory {
+	for _, channel := range app.workspace.StatusChannels {
+		err := app.sendChannelReminders(channel.ID, now)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Println("success.")
+	return nil
+}
+
+func (app *CSPSlack) sendChannelReminders(channelID string, now bool) error {
+	c := config()
+	var pinnedMessageLinks, escalated []ReminderInfo
+	for _, message := range app.channelHistory[channelID] {
 		// Don't send reminders for messages that don't mention the bot.
 		// That way, we can still pin messages.
-		if !botActionablyMentioned(message.Text) {
+		if !app.isStatusUpdate(channelID, message) {
 			continue
 		}
 		if len(message.PinnedTo) > 0 {
-			ts := slackTSToHumanTime(message.Timestamp)
-			status := GetPinnedMessageStatus(message.Reactions)
+			updateID := slackUpdateID(channelID, message.Timestamp)
+			if store.snoozed(updateID, time.Now()) {
+				fmt.Println("Message was snoozed. Ignoring.")
+				continue
+			}
+			posted := slackTSToTime(message.Timestamp)
+			status := GetPinnedMessageStatus(message.Reactions, app.workspace.BotID)
+
+			// Don't bother if the message hasn't been up long enough for
+			// its severity
+			pinnedFor := time.Since(posted)
+			if !reminderDue(emojiSeverity(status), pinnedFor, now) {
+				fmt.Println("Message not pinned for long enough. Ignoring.")
+				continue
+			}
 
-			// Don't bother if the message hasn't been up longer than a day
-			t, err := time.Parse("2006-01-02 15:04:05 MST", ts)
-			if err == nil {
-				if time.Since(t) < 24*time.Hour && now == false {
-					fmt.Println("Message not pinned for long enough. Ignoring.")
-					continue
-				}
+			// Updates from the compose modal were posted by us, for someone
+			author := message.User
+			if composed, ok := app.composedUpdate(channelID, message); ok && composed.Author != "" {
+				author = composed.Author
 			}
 
 			// Grab permalink to send final reminder message.
 			permalink, err := app.slackSocket.GetPermalink(&slack.PermalinkParameters{
-				Channel: config.SlackStatusChannelID,
+				Channel: channelID,
 				Ts:      message.Timestamp,
 			})
 			if err != nil {
 				return err
 			}
-			pinnedMessageLinks = append(pinnedMessageLinks, ReminderInfo{message.User, permalink, ts, status})
+			reminder := ReminderInfo{author, permalink, posted, status, updateID}
+			if c.ReminderMode == ReminderDM && (escalationDue(emojiSeverity(status), pinnedFor) || author == "" || author == app.workspace.BotID) {
+				escalated = append(escalated, reminder)
+			} else {
+				pinnedMessageLinks = append(pinnedMessageLinks, reminder)
+			}
 			fmt.Println("Found message.")
 		}
 	}
 
-	if len(pinnedMessageLinks) == 0 {
-		fmt.Println("No messages pinned.")
+	if len(pinnedMessageLinks) == 0 && len(escalated) == 0 {
+		fmt.Println("No messages pinned in", channelID)
 		return nil
 	}
 
-	// Send summary message
-	summaryMessage := fmt.Sprintln("Hello, Admins.\nThe following messages are currently pinned.")
-	for _, m := range pinnedMessageLinks {
-		var parsedStatus string
-		if m.status == "" {
-			parsedStatus = "•"
-		} else {
-			parsedStatus = fmt.Sprintf(":%s:", m.status)
-		}
-		summaryMessage += fmt.Sprintf("%s <@%s> <%s|Since %s>\n\n", parsedStatus, m.userID, m.link, m.ts)
+	if c.ReminderMode == ReminderDM {
+		return app.sendDMReminders(channelID, pinnedMessageLinks, escalated)
 	}
 
-	summaryMessage += fmt.Sprintf("It might be time to unpin them if they are no longer relevant.")
+	// Send summary message, with buttons to deal with each one right there
+	_, _, err := app.slackSocket.PostMessage(
+		channelID,
+		slack.MsgOptionText("Hello, Admins. Some messages have been pinned for a while.", false),
+		slack.MsgOptionBlocks(CreateReminderMsg("Hello, Admins.\nThe following messages are currently pinned.", pinnedMessageLinks)...),
+	)
+	return err
+}
 
+// Reminds each author about their own pins. Anything that's been ignored for
+// too long goes to the channel instead, pinging the escalation group if
+// there is one.
+func (app *CSPSlack) sendDMReminders(channelID string, reminders []ReminderInfo, escalated []ReminderInfo) error {
+	byAuthor := make(map[string][]ReminderInfo)
+	var authors []string
+	for _, reminder := range reminders {
+		if _, ok := byAuthor[reminder.userID]; !ok {
+			authors = append(authors, reminder.userID)
+		}
+		byAuthor[reminder.userID] = append(byAuthor[reminder.userID], reminder)
+	}
+	for _, author := range authors {
+		_, _, err := app.slackSocket.PostMessage(
+			author,
+			slack.MsgOptionText("Some of your status updates have been pinned for a while.", false),
+			slack.MsgOptionBlocks(CreateReminderMsg(fmt.Sprintf("Hi! These updates you posted in <#%s> are still pinned on the status page.", channelID), byAuthor[author])...),
+		)
+		if err != nil {
+			// They'll hear about it once it escalates
+			log.Printf("Could not remind %s: %s\n", author, err)
+		}
+	}
+
+	if len(escalated) == 0 {
+		return nil
+	}
+	intro := "These updates have been pinned for a long time and nobody has dealt with them yet."
+	if app.workspace.EscalationGroup != "" {
+		intro = fmt.Sprintf("<!subteam^%s> %s", app.workspace.EscalationGroup, intro)
+	}
 	_, _, err := app.slackSocket.PostMessage(
-		config.SlackStatusChannelID,
-		slack.MsgOptionText(summaryMessage, false),
+		channelID,
+		slack.MsgOptionText("Some messages have been pinned for a long time.", false),
+		slack.MsgOptionBlocks(CreateReminderMsg(intro, escalated)...),
 	)
+	return err
+}
+
+// Posts a message from us in the first status channel. Since it doesn't
+// mention us, it stays off the page.
+func (app *CSPSlack) Announce(message string) error {
+	if len(app.workspace.StatusChannels) == 0 {
+		return nil
+	}
+	_, _, err := app.slackSocket.PostMessage(app.workspace.StatusChannels[0].ID, slack.MsgOptionText(message, false))
+	return err
+}
+
+// Takes an update off the page and says why in its thread
+func (app *CSPSlack) Unpin(updateID string, note string) error {
+	channelID, ts := parseSlackUpdateID(updateID)
+	err := app.slackSocket.RemovePin(channelID, slack.NewRefToMessage(channelID, ts))
 	if err != nil {
 		return err
 	}
-
-	fmt.Println("success.")
-	return nil
+	_, _, err = app.slackSocket.PostMessage(channelID, slack.MsgOptionTS(ts), slack.MsgOptionText(note, false))
+	return err
 }
 
 func (app *CSPSlack) Run() {
@@ -215,6 +706,8 @@ func (app *CSPSlack) Run() {
 				e.handleEventAPIEvent()
 			case socketmode.EventTypeInteractive:
 				e.handleInteractiveEvent()
+			case socketmode.EventTypeSlashCommand:
+				e.handleSlashCommand()
 			}
 
 			// If necessary, sync our cached Slack messages
@@ -229,6 +722,7 @@ func (app *CSPSlack) Run() {
 				if err != nil {
 					log.Println(err.Error())
 				}
+				app.refreshHomes()
 				app.shouldUpdate = false
 			}
 		}
@@ -279,8 +773,9 @@ func (app *CSPSlack) resolveChannelName(channelID string) (string, error) {
 func (app *CSPSlack) getThreadConversation(channelID string, threadTs string) (conversation []slack.Message, err error) {
 	// Get the conversation history
 	params := slack.GetConversationRepliesParameters{
-		ChannelID: channelID,
-		Timestamp: threadTs,
+		ChannelID:          channelID,
+		Timestamp:          threadTs,
+		IncludeAllMetadata: true,
 	}
 	conversation, _, _, err = app.slackAPI.GetConversationReplies(&params)
 	if err != nil {
@@ -290,28 +785,151 @@ func (app *CSPSlack) getThreadConversation(channelID string, threadTs string) (c
 }
 
 func (app *CSPSlack) getChannelHistory() (err error) {
-	log.Println("Fetching channel history from: ", config.SlackStatusChannelID)
-	limit, _ := strconv.Atoi(config.SlackTruncation)
-	params := slack.GetConversationHistoryParameters{
-		ChannelID: config.SlackStatusChannelID,
-		Oldest:    "0",   // Retrieve messages from the beginning of time
-		Inclusive: true,  // Include the oldest message
-		Limit:     limit, // Only get 100 messages
+	limit, _ := strconv.Atoi(app.workspace.Truncation)
+	channelHistory := make(map[string][]slack.Message)
+	for _, channel := range app.workspace.StatusChannels {
+		log.Println("Fetching channel history from: ", channel.ID)
+		params := slack.GetConversationHistoryParameters{
+			ChannelID: channel.ID,
+			Oldest:    "0",   // Retrieve messages from the beginning of time
+			Inclusive: true,  // Include the oldest message
+			Limit:     limit, // Only get 100 messages
+
+			IncludeAllMetadata: true, // So we can tell which updates we composed
+		}
+
+		var history *slack.GetConversationHistoryResponse
+		history, err = app.slackSocket.GetConversationHistory(&params)
+		if err != nil {
+			return err
+		}
+		channelHistory[channel.ID] = history.Messages
 	}
+	app.channelHistory = channelHistory
+	return nil
+}
 
-	var history *slack.GetConversationHistoryResponse
-	history, err = app.slackSocket.GetConversationHistory(&params)
-	app.channelHistory = history.Messages
-	return err
+// Composed updates carry what we know about them in their metadata as well,
+// so they're still ours after a restart without a state file
+const composedUpdateEvent = "csp_composed_update"
+
+func composedUpdateMetadata(composed ComposedUpdate) slack.SlackMetadata {
+	payload := map[string]interface{}{
+		"author":   composed.Author,
+		"internal": composed.Internal,
+	}
+	if !composed.ExpectedResolution.IsZero() {
+		payload["expected_resolution"] = composed.ExpectedResolution.Unix()
+	}
+	return slack.SlackMetadata{EventType: composedUpdateEvent, EventPayload: payload}
+}
+
+// What we know about an update we posted for someone, if we did. The store
+// has it if it's been kept, and the message's metadata has it otherwise.
+func (app *CSPSlack) composedUpdate(channelID string, message slack.Message) (composed ComposedUpdate, ok bool) {
+	if composed, ok := store.composedUpdate(slackUpdateID(channelID, message.Timestamp)); ok {
+		return composed, true
+	}
+	if message.Metadata.EventType != composedUpdateEvent || message.User != app.workspace.BotID {
+		return composed, false
+	}
+	payload := message.Metadata.EventPayload
+	composed.Author, _ = payload["author"].(string)
+	composed.Internal, _ = payload["internal"].(bool)
+	if at, ok := payload["expected_resolution"].(float64); ok {
+		composed.ExpectedResolution = time.Unix(int64(at), 0)
+	}
+	return composed, true
+}
+
+// Status updates are messages from publishers that mention us, or that we
+// posted from the compose modal. Messages that mention us but are empty don't
+// count!
+func (app *CSPSlack) isStatusUpdate(channelID string, message slack.Message) bool {
+	if _, ok := app.composedUpdate(channelID, message); ok {
+		return true
+	}
+	return botActionablyMentioned(message.Text, app.workspace.BotID) && app.canPublish(message.User)
+}
+
+// How long we trust our idea of who's in the publisher groups
+const publisherGroupsTTL = 5 * time.Minute
+
+// Whether someone is allowed to put things on the page, change them, or take
+// them down. If nobody in particular has been allowed, everybody is.
+func (app *CSPSlack) canPublish(userID string) bool {
+	if len(app.workspace.Publishers) == 0 && len(app.workspace.PublisherGroups) == 0 {
+		return true
+	}
+	if userID == app.workspace.BotID || stringInSlice(app.workspace.Publishers, userID) {
+		return true
+	}
+	if len(app.workspace.PublisherGroups) == 0 {
+		return false
+	}
+
+	members, ok := app.publisherGroups.get()
+	if !ok {
+		members, ok = app.fetchPublisherGroups()
+	}
+	if !ok {
+		// We've never managed to find out who's in the groups, and locking
+		// everyone out would empty the page
+		return true
+	}
+	return members[userID]
+}
+
+// The members we know about, and whether they're fresh enough to use
+func (cache *publisherGroupCache) get() (map[string]bool, bool) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.members, cache.members != nil && time.Since(cache.fetched) <= publisherGroupsTTL
+}
+
+// Asks Slack who's in the publisher groups. This can take a while, so it
+// happens outside the lock. If Slack won't tell us, we keep using the last
+// list we got, if we ever got one.
+func (app *CSPSlack) fetchPublisherGroups() (map[string]bool, bool) {
+	cache := app.publisherGroups
+	members := make(map[string]bool)
+	for _, group := range app.workspace.PublisherGroups {
+		users, err := app.slackAPI.GetUserGroupMembers(group)
+		if err != nil {
+			log.Printf("Could not get members of %s: %s\n", group, err)
+			cache.mu.Lock()
+			defer cache.mu.Unlock()
+			return cache.members, cache.members != nil
+		}
+		for _, user := range users {
+			members[user] = true
+		}
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.members = members
+	cache.fetched = time.Now()
+	return members, true
+}
+
+// Whether we should be paying attention to a channel at all
+func (app *CSPSlack) isStatusChannel(channelID string) bool {
+	for _, channel := range app.workspace.StatusChannels {
+		if channel.ID == channelID {
+			return true
+		}
+	}
+	return false
 }
 
 func (app *CSPSlack) getSingleMessage(channelID string, oldest string) (message slack.Message, err error) {
 	log.Println("Fetching channel history...")
 	params := slack.GetConversationHistoryParameters{
-		ChannelID: channelID,
-		Oldest:    oldest,
-		Inclusive: true,
-		Limit:     1,
+		ChannelID:          channelID,
+		Oldest:             oldest,
+		Inclusive:          true,
+		Limit:              1,
+		IncludeAllMetadata: true,
 	}
 
 	var history *slack.GetConversationHistoryResponse
@@ -325,28 +943,53 @@ func (app *CSPSlack) getSingleMessage(channelID string, oldest string) (message
 	return history.Messages[0], err
 }
 
-func (app *CSPSlack) isBotMentioned(timestamp string) (isMentioned bool, err error) {
+// Finds a message whether it's in a thread or not
+func (app *CSPSlack) getMessage(channelID string, timestamp string, threadTs string) (message slack.Message, err error) {
+	if threadTs == "" || threadTs == timestamp {
+		return app.getSingleMessage(channelID, timestamp)
+	}
+	conversation, err := app.getThreadConversation(channelID, threadTs)
+	if err != nil {
+		return message, err
+	}
+	for _, reply := range conversation {
+		if reply.Timestamp == timestamp {
+			return reply, nil
+		}
+	}
+	return message, errors.New("Message not found in thread.")
+}
+
+func (app *CSPSlack) isBotMentioned(channelID string, timestamp string) (isMentioned bool, err error) {
+	// Composed updates don't need to mention us
+	if _, ok := store.composedUpdate(slackUpdateID(channelID, timestamp)); ok {
+		return true, nil
+	}
 	history, err := app.slackSocket.GetConversationHistory(
 		&slack.GetConversationHistoryParameters{
-			ChannelID: config.SlackStatusChannelID,
-			Inclusive: true,
-			Latest:    timestamp,
-			Oldest:    timestamp,
-			Limit:     1,
+			ChannelID:          channelID,
+			Inclusive:          true,
+			Latest:             timestamp,
+			Oldest:             timestamp,
+			Limit:              1,
+			IncludeAllMetadata: true,
 		},
 	)
 	if err != nil {
 		return false, err
 	}
 	if len(history.Messages) > 0 {
-		return strings.Contains(history.Messages[0].Text, config.SlackBotID), nil
+		if _, ok := app.composedUpdate(channelID, history.Messages[0]); ok {
+			return true, nil
+		}
+		return strings.Contains(history.Messages[0].Text, app.workspace.BotID), nil
 	}
 	return false, err
 }
 
-func (app *CSPSlack) clearReactions(timestamp string, focusReactions []string) error {
+func (app *CSPSlack) clearReactions(channelID string, timestamp string, focusReactions []string) error {
 	ref := slack.ItemRef{
-		Channel:   config.SlackStatusChannelID,
+		Channel:   channelID,
 		Timestamp: timestamp,
 	}
 	reactions, err := app.slackSocket.GetReactions(ref, slack.NewGetReactionsParameters())
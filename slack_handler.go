@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
@@ -31,16 +34,20 @@ func (h *CSPSlackEvtHandler) handleEventAPIEvent() {
 		innerEvent := eventsAPIEvent.InnerEvent
 		switch ev := innerEvent.Data.(type) {
 		case *slackevents.PinAddedEvent:
-			h.shouldUpdate = true
+			if h.isStatusChannel(ev.Channel) {
+				h.shouldUpdate = true
+			}
 		case *slackevents.PinRemovedEvent:
-			h.shouldUpdate = true
+			if h.isStatusChannel(ev.Channel) {
+				h.shouldUpdate = true
+			}
 		case *slackevents.ReactionRemovedEvent:
-			if ev.User == config.SlackBotID {
+			if ev.User == h.workspace.BotID || !h.isStatusChannel(ev.Item.Channel) || !h.canPublish(ev.User) {
 				return
 			}
 			reaction := ev.Reaction
 			h.slackSocket.RemoveReaction(reaction, slack.ItemRef{
-				Channel:   config.SlackStatusChannelID,
+				Channel:   ev.Item.Channel,
 				Timestamp: ev.Item.Timestamp,
 			})
 			h.shouldUpdate = true
@@ -48,6 +55,15 @@ func (h *CSPSlackEvtHandler) handleEventAPIEvent() {
 			h.handleReactionAddedEvent(ev)
 		case *slackevents.MessageEvent:
 			h.handleMessageEvent(ev)
+		case *slackevents.AppHomeOpenedEvent:
+			if ev.Tab != "home" {
+				return
+			}
+			h.homeUsers[ev.User] = true
+			_, err := h.slackAPI.PublishView(ev.User, CreateHomeView(h.homeUpdates(), upcomingMaintenance(), h.canPublish(ev.User)), "")
+			if err != nil {
+				log.Printf("Could not publish App Home for %s: %s\n", ev.User, err)
+			}
 		default:
 			log.Println("no handler for event of given type")
 		}
@@ -57,28 +73,34 @@ func (h *CSPSlackEvtHandler) handleEventAPIEvent() {
 
 func (h *CSPSlackEvtHandler) handleReactionAddedEvent(ev *slackevents.ReactionAddedEvent) {
 	reaction := ev.Reaction
-	botMentioned, err := h.isBotMentioned(ev.Item.Timestamp)
+	if !h.isStatusChannel(ev.Item.Channel) {
+		return
+	}
+	botMentioned, err := h.isBotMentioned(ev.Item.Channel, ev.Item.Timestamp)
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	if ev.User == config.SlackBotID || !isRelevantReaction(reaction) || (!botMentioned) {
+	if ev.User == h.workspace.BotID || !isRelevantReaction(reaction) || (!botMentioned) {
+		return
+	}
+	if !h.canPublish(ev.User) {
+		log.Printf("%s isn't allowed to change severity\n", ev.User)
 		return
 	}
 	// If necessary, remove a conflicting reaction
 	if isRelevantReaction(reaction) {
 		h.clearReactions(
+			ev.Item.Channel,
 			ev.Item.Timestamp,
-			[]string{
-				config.StatusOKEmoji,
-				config.StatusWarnEmoji,
-				config.StatusErrorEmoji,
-			},
+			severityEmojis(),
 		)
 	}
+	h.requestApproval(ev.Item.Channel, ev.Item.Timestamp, ev.User, emojiSeverity(reaction))
+
 	// Mirror the reaction on the message
 	h.slackSocket.AddReaction(reaction, slack.NewRefToMessage(
-		config.SlackStatusChannelID,
+		ev.Item.Channel,
 		ev.Item.Timestamp,
 	))
 	h.shouldUpdate = true
@@ -88,6 +110,9 @@ func (h *CSPSlackEvtHandler) handleMessageEvent(ev *slackevents.MessageEvent) {
 	// If a message mentioning us gets added or deleted, then
 	// do something
 	log.Printf("Message type: %s\n", ev.SubType)
+	if !h.isStatusChannel(ev.Channel) {
+		return
+	}
 
 	// If the message was deleted, then update the page.
 	// If LITERALLY ANYTHING ELSE happened, bail
@@ -104,29 +129,48 @@ func (h *CSPSlackEvtHandler) handleMessageEvent(ev *slackevents.MessageEvent) {
 
 	// If the bot was mentioned in this message, then we should probably
 	// re-build the page, and if not, we should bail.
-	botID := fmt.Sprintf("<@%s>", config.SlackBotID)
+	botID := fmt.Sprintf("<@%s>", h.workspace.BotID)
 	if strings.Contains(ev.Text, botID) {
 		h.shouldUpdate = true
 	} else {
 		return
 	}
 
+	// Mentioning us puts things on the page, so only publishers get to
+	if !h.canPublish(ev.User) {
+		h.notifyDenied(ev.Channel, ev.User)
+		return
+	}
+
+	// Replies in a thread are follow-ups to an update we already asked about,
+	// so don't prompt for them. They can still move the incident along.
+	if ev.ThreadTimeStamp != "" && ev.ThreadTimeStamp != ev.TimeStamp {
+		h.handleThreadReply(ev, strings.Replace(ev.Text, botID, "", -1))
+		return
+	}
+
 	// HACK: If we're still here, it means we got mentioned, and should
 	// do something about it. We do this instead of an AppMention because
 	// there does not seem to be any way to not fire an AppMentionEvent
 	// if a message is edited
 	log.Printf("Got mentioned. Timestamp is: %s. ThreadTimestamp is: %s\n", ev.TimeStamp, ev.ThreadTimeStamp)
 
-	channelName, err := h.resolveChannelName(config.SlackForwardChannelID)
+	if config().Drafts {
+		h.saveDraft(ev.Channel, ev.TimeStamp, ev.User)
+	}
+	h.requestApproval(ev.Channel, ev.TimeStamp, ev.User, "")
+
+	channelName, err := h.resolveChannelName(h.workspace.ForwardChannelID)
 	if err != nil {
 		log.Printf("Could not resolve channel name: %s\n", err)
 		return
 	}
 	blocks := CreateUpdateResponseMsg(channelName, ev.User)
-	_, _, err = h.slackSocket.PostMessage(config.SlackStatusChannelID, slack.MsgOptionTS(ev.TimeStamp), slack.MsgOptionBlocks(blocks...))
+	_, _, err = h.slackSocket.PostMessage(ev.Channel, slack.MsgOptionTS(ev.TimeStamp), slack.MsgOptionBlocks(blocks...))
 	if err != nil {
 		log.Printf("Error posting ephemeral message: %s", err)
 	}
+	h.postPreview(ev.Channel, ev.TimeStamp)
 }
 
 func (h *CSPSlackEvtHandler) handleInteractiveEvent() {
@@ -144,14 +188,73 @@ func (h *CSPSlackEvtHandler) handleInteractiveEvent() {
 		// Check which button was pressed
 		for _, action := range callback.ActionCallback.BlockActions {
 			switch action.ActionID {
-			case CSPSetOK, CSPSetWarn, CSPSetError, CSPCancel:
+			case CSPCancel:
 				h.handlePromptInteraction(callback, action)
+			case CSPTagComponents:
+				h.openComponentsModal(callback)
+			case CSPHomeResolve, CSPHomeUnpin, CSPHomeFollowUp:
+				h.handleHomeInteraction(callback, action)
+			case CSPApprove:
+				h.handleApproveInteraction(callback, action)
+			case CSPPublishDraft:
+				h.handlePublishDraftInteraction(callback, action)
+			case CSPMaintenanceCancel:
+				h.handleCancelMaintenanceInteraction(callback, action)
+			case CSPPinExpiry:
+				h.handlePinExpiryInteraction(callback, action)
+			case CSPReminderUnpin, CSPReminderResolve, CSPReminderSnooze:
+				h.handleReminderInteraction(callback, action)
+			case CSPPreviewLink:
+				// Just a link, Slack opens it for us
+			default:
+				switch {
+				case strings.HasPrefix(action.ActionID, CSPSetSeverityPrefix):
+					h.handlePromptInteraction(callback, action)
+				case strings.HasPrefix(action.ActionID, CSPHomeSetSeverityPrefix):
+					h.handleHomeInteraction(callback, action)
+				case strings.HasPrefix(action.ActionID, CSPSetStatePrefix):
+					h.handleStateInteraction(callback, action)
+				}
+			}
+		}
+	case slack.InteractionTypeViewSubmission:
+		switch callback.View.CallbackID {
+		case CSPComponentsModal:
+			h.handleComponentsSubmission(callback)
+		case CSPComposeModal:
+			h.handleComposeSubmission(callback)
+		case CSPPublishModal:
+			h.handlePublishSubmission(callback)
+		case CSPFollowUpModal:
+			h.handleFollowUpSubmission(callback)
+		case CSPMaintenanceModal:
+			if response := h.handleMaintenanceSubmission(callback); response != nil {
+				payload = response
 			}
 		}
+	case slack.InteractionTypeMessageAction:
+		log.Printf("Got message shortcut: %s", callback.CallbackID)
+		switch callback.CallbackID {
+		case CSPPublishShortcut:
+			h.handlePublishShortcut(callback)
+		}
 	case slack.InteractionTypeShortcut:
 		log.Printf("Got shortcut: %s", callback.CallbackID)
-		if callback.CallbackID == CSPUpdateStatusPage {
+		switch callback.CallbackID {
+		case CSPUpdateStatusPage:
 			h.shouldUpdate = true
+		case CSPComposeShortcut:
+			if !h.canPublish(callback.User.ID) {
+				h.notifyDenied("", callback.User.ID)
+				break
+			}
+			h.openComposeModal(callback.TriggerID, "")
+		case CSPMaintenanceShortcut:
+			if !h.canPublish(callback.User.ID) {
+				h.notifyDenied("", callback.User.ID)
+				break
+			}
+			h.openMaintenanceModal(callback.TriggerID)
 		}
 	default:
 		log.Println("no handler for event of given type")
@@ -162,6 +265,10 @@ func (h *CSPSlackEvtHandler) handleInteractiveEvent() {
 
 func (h *CSPSlackEvtHandler) handlePromptInteraction(callback slack.InteractionCallback, action *slack.BlockAction) {
 	log.Printf("Block Action Detected: %s\n", action.ActionID)
+	if !h.canPublish(callback.User.ID) {
+		h.notifyDenied(callback.Channel.ID, callback.User.ID)
+		return
+	}
 	itemRef := slack.ItemRef{
 		Channel:   callback.Channel.ID,
 		Timestamp: callback.Container.ThreadTs,
@@ -190,50 +297,668 @@ func (h *CSPSlackEvtHandler) handlePromptInteraction(callback slack.InteractionC
 				break
 			}
 
-			botID := fmt.Sprintf("<@%s>", config.SlackBotID)
+			botID := fmt.Sprintf("<@%s>", h.workspace.BotID)
 			strippedStatusUpdate := strings.Replace(messageText.Text, botID, "", -1)
 
-			_, _, err = h.slackSocket.PostMessage(config.SlackForwardChannelID, slack.MsgOptionText(strippedStatusUpdate, false))
+			_, _, err = h.slackSocket.PostMessage(h.workspace.ForwardChannelID, slack.MsgOptionText(strippedStatusUpdate, false))
+		}
+	}
+
+	// Clear any old reactions, then add the reaction we want. The levels might
+	// have been reloaded since the prompt went out, so check it's still one.
+	if severity, ok := strings.CutPrefix(action.ActionID, CSPSetSeverityPrefix); ok {
+		if _, known := severityLevel(severity); known {
+			h.clearReactions(
+				callback.Channel.ID,
+				callback.Container.ThreadTs,
+				severityEmojis(),
+			)
+			h.requestApproval(callback.Channel.ID, callback.Container.ThreadTs, callback.User.ID, severity)
+			err := h.slackSocket.AddReaction(severityEmoji(severity), itemRef)
+			if err != nil {
+				// Handle the error
+				h.slackSocket.Debugf("Error adding reaction: %v", err)
+			}
+		} else {
+			log.Printf("Unknown severity level '%s', leaving the reactions alone\n", severity)
 		}
 	}
+	_, _, err := h.slackSocket.DeleteMessage(callback.Channel.ID, callback.Container.MessageTs)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (h *CSPSlackEvtHandler) openComponentsModal(callback slack.InteractionCallback) {
+	if !h.canPublish(callback.User.ID) {
+		h.notifyDenied(callback.Channel.ID, callback.User.ID)
+		return
+	}
+	updateID := slackUpdateID(callback.Channel.ID, callback.Container.ThreadTs)
+	_, err := h.slackAPI.OpenView(callback.TriggerID, CreateComponentsModal(updateID, store.updateComponents(updateID)))
+	if err != nil {
+		log.Printf("Could not open components modal: %s\n", err)
+	}
+}
+
+func (h *CSPSlackEvtHandler) handleComponentsSubmission(callback slack.InteractionCallback) {
+	updateID := callback.View.PrivateMetadata
+	components := selectedValues(callback.View.State, CSPComponentsBlock, CSPComponentsBlock)
+	log.Printf("Tagging %s with components %v\n", updateID, components)
+	err := store.setUpdateComponents(updateID, components)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	h.shouldUpdate = true
+}
+
+func (h *CSPSlackEvtHandler) handleStateInteraction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	if !h.canPublish(callback.User.ID) {
+		h.notifyDenied(callback.Channel.ID, callback.User.ID)
+		return
+	}
+	state := strings.TrimPrefix(action.ActionID, CSPSetStatePrefix)
+	updateID := slackUpdateID(callback.Channel.ID, callback.Container.ThreadTs)
+	log.Printf("Marking %s as %s\n", updateID, state)
+	err := store.addIncidentState(updateID, state, time.Now())
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	h.shouldUpdate = true
+}
+
+func (h *CSPSlackEvtHandler) handleThreadReply(ev *slackevents.MessageEvent, text string) {
+	state, ok := parseIncidentState(text)
+	if !ok {
+		return
+	}
+	updateID := slackUpdateID(ev.Channel, ev.ThreadTimeStamp)
+	log.Printf("Marking %s as %s\n", updateID, state)
+	err := store.addIncidentState(updateID, state, slackTSToTime(ev.TimeStamp))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	// Let them know we saw it
+	err = h.slackSocket.AddReaction("white_check_mark", slack.NewRefToMessage(ev.Channel, ev.TimeStamp))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (h *CSPSlackEvtHandler) handleSlashCommand() {
+	cmd, ok := h.evt.Data.(slack.SlashCommand)
+	if !ok {
+		fmt.Printf("Ignored %+v\n", h.evt)
+		return
+	}
+	h.slackSocket.Ack(*h.evt.Request)
+
+	log.Printf("Got slash command: %s\n", cmd.Command)
+	if !h.canPublish(cmd.UserID) {
+		h.notifyDenied(cmd.ChannelID, cmd.UserID)
+		return
+	}
+	switch cmd.Command {
+	case CSPComposeCommand:
+		if strings.TrimSpace(cmd.Text) == "maintenance" {
+			h.openMaintenanceModal(cmd.TriggerID)
+			break
+		}
+		h.openComposeModal(cmd.TriggerID, cmd.ChannelID)
+	}
+}
+
+// Opens the compose modal, with the channel it was opened from picked if
+// that's a status channel.
+func (h *CSPSlackEvtHandler) openComposeModal(triggerID string, channelID string) {
+	names := make(map[string]string)
+	for _, channel := range h.workspace.StatusChannels {
+		name, err := h.resolveChannelName(channel.ID)
+		if err != nil {
+			log.Printf("Could not resolve channel name: %s\n", err)
+			name = channel.ID
+		}
+		names[channel.ID] = name
+	}
+
+	var forwardChannelName string
+	if h.workspace.ForwardChannelID != "" {
+		var err error
+		forwardChannelName, err = h.resolveChannelName(h.workspace.ForwardChannelID)
+		if err != nil {
+			log.Printf("Could not resolve channel name: %s\n", err)
+		}
+	}
+
+	modal := CreateComposeModal(h.workspace.StatusChannels, names, channelID, forwardChannelName)
+	_, err := h.slackAPI.OpenView(triggerID, modal)
+	if err != nil {
+		log.Printf("Could not open compose modal: %s\n", err)
+	}
+}
+
+// Posts an update written in the compose modal to the status channel, then
+// does everything the prompt would have done for it.
+func (h *CSPSlackEvtHandler) handleComposeSubmission(callback slack.InteractionCallback) {
+	values := callback.View.State.Values
+	text := values[CSPComposeMessage][CSPComposeMessage].Value
+	severity := values[CSPComposeSeverity][CSPComposeSeverity].SelectedOption.Value
+	options := selectedValues(callback.View.State, CSPComposeOptions, CSPComposeOptions)
+	components := selectedValues(callback.View.State, CSPComponentsBlock, CSPComponentsBlock)
+	visibility := values[CSPComposeVisibility][CSPComposeVisibility].SelectedOption.Value
+	composed := ComposedUpdate{
+		Author:   callback.User.ID,
+		Internal: visibility == CSPVisibilityInternal,
+	}
+	if resolution := values[CSPComposeResolution][CSPComposeResolution].SelectedDateTime; resolution != 0 {
+		composed.ExpectedResolution = time.Unix(resolution, 0)
+	}
+
+	channelID := values[CSPComposeChannel][CSPComposeChannel].SelectedOption.Value
+	if channelID == "" && len(h.workspace.StatusChannels) > 0 {
+		channelID = h.workspace.StatusChannels[0].ID
+	}
+
+	ts, err := h.postComposedUpdate(channelID, "", text, fmt.Sprintf("Posted for <@%s>", callback.User.ID), composed)
+	if err != nil {
+		log.Printf("Could not post composed update: %s\n", err)
+		return
+	}
+	updateID := slackUpdateID(channelID, ts)
+	if visibility == CSPVisibilityDraft {
+		h.saveDraft(channelID, ts, callback.User.ID)
+	}
+	if choice := values[CSPPinExpiry][CSPPinExpiry].SelectedOption.Value; choice != "" {
+		err = choosePinExpiry(updateID, choice, time.Now())
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	h.requestApproval(channelID, ts, callback.User.ID, severity)
+	if len(components) > 0 {
+		err = store.setUpdateComponents(updateID, components)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	itemRef := slack.NewRefToMessage(channelID, ts)
+	if emoji := severityEmoji(severity); emoji != "" {
+		err = h.slackSocket.AddReaction(emoji, itemRef)
+		if err != nil {
+			log.Printf("Error adding reaction: %s\n", err)
+		}
+	}
+	if stringInSlice(options, CSPPin) {
+		err = h.slackSocket.AddPin(channelID, itemRef)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	if stringInSlice(options, CSPForward) {
+		_, _, err = h.slackSocket.PostMessage(h.workspace.ForwardChannelID, slack.MsgOptionText(text, false))
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	if !composed.Internal {
+		h.postPreview(channelID, ts)
+	}
+	h.shouldUpdate = true
+}
+
+// Posts an update (or a follow-up to one, if threadTs is set) to a status
+// channel as the bot, on someone else's behalf. The plain text is kept as-is
+// since that's what goes on the page, and the attribution goes underneath
+// where only Slack will see it.
+func (h *CSPSlackEvtHandler) postComposedUpdate(channelID string, threadTs string, text string, attribution string, composed ComposedUpdate) (ts string, err error) {
+	options := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, attribution, false, false)),
+		),
+		slack.MsgOptionMetadata(composedUpdateMetadata(composed)),
+	}
+	if threadTs != "" {
+		options = append(options, slack.MsgOptionTS(threadTs))
+	}
+	_, ts, err = h.slackSocket.PostMessage(channelID, options...)
+	if err != nil {
+		return "", err
+	}
+	updateID := slackUpdateID(channelID, ts)
+	log.Printf("Posted update %s for %s\n", updateID, composed.Author)
+	return ts, store.setComposedUpdate(updateID, composed)
+}
+
+// Where a message being published came from. It rides along in the publish
+// modal's metadata.
+type publishSource struct {
+	ChannelID       string `json:"channel"`
+	Timestamp       string `json:"ts"`
+	ThreadTimestamp string `json:"thread_ts,omitempty"`
+}
+
+// Somebody wants to put a message from some other channel on the page. If
+// there's more than one status channel, ask them which one first.
+func (h *CSPSlackEvtHandler) handlePublishShortcut(callback slack.InteractionCallback) {
+	if !h.canPublish(callback.User.ID) {
+		h.notifyDenied(callback.Channel.ID, callback.User.ID)
+		return
+	}
+	source := publishSource{
+		ChannelID:       callback.Channel.ID,
+		Timestamp:       callback.Message.Timestamp,
+		ThreadTimestamp: callback.Message.ThreadTimestamp,
+	}
+	if len(h.workspace.StatusChannels) == 1 {
+		h.publishMessage(source, h.workspace.StatusChannels[0].ID, callback.User.ID)
+		return
+	}
+
+	names := make(map[string]string)
+	for _, channel := range h.workspace.StatusChannels {
+		name, err := h.resolveChannelName(channel.ID)
+		if err != nil {
+			log.Printf("Could not resolve channel name: %s\n", err)
+			name = channel.ID
+		}
+		names[channel.ID] = name
+	}
+	metadata, err := json.Marshal(source)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	_, err = h.slackAPI.OpenView(callback.TriggerID, CreatePublishModal(string(metadata), h.workspace.StatusChannels, names))
+	if err != nil {
+		log.Printf("Could not open publish modal: %s\n", err)
+	}
+}
+
+func (h *CSPSlackEvtHandler) handlePublishSubmission(callback slack.InteractionCallback) {
+	var source publishSource
+	err := json.Unmarshal([]byte(callback.View.PrivateMetadata), &source)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	channelID := callback.View.State.Values[CSPComposeChannel][CSPComposeChannel].SelectedOption.Value
+	h.publishMessage(source, channelID, callback.User.ID)
+}
+
+// Copies a message into a status channel, crediting whoever wrote it and
+// linking back to where it came from, then asks the person publishing it what
+// kind of alert it is like we would for any other update.
+func (h *CSPSlackEvtHandler) publishMessage(source publishSource, channelID string, publisher string) {
+	message, err := h.getMessage(source.ChannelID, source.Timestamp, source.ThreadTimestamp)
+	if err != nil {
+		log.Printf("Could not get message to publish: %s\n", err)
+		h.notifyUser(source.ChannelID, publisher, "Sorry, I couldn't read that message. Is the status page bot in this channel?")
+		return
+	}
+	permalink, err := h.slackSocket.GetPermalink(&slack.PermalinkParameters{
+		Channel: source.ChannelID,
+		Ts:      source.Timestamp,
+	})
+	if err != nil {
+		log.Printf("Could not get permalink: %s\n", err)
+		h.notifyUser(source.ChannelID, publisher, "Sorry, I couldn't link back to that message, so it wasn't published.")
+		return
+	}
+
+	// Alerts from integrations don't have a user behind them, so the page
+	// credits whoever published them instead
+	author, postedBy := message.User, fmt.Sprintf("<@%s>", message.User)
+	if author == "" {
+		author, postedBy = publisher, integrationName(message)
+	}
+	botID := fmt.Sprintf("<@%s>", h.workspace.BotID)
+	text := strings.TrimSpace(strings.Replace(message.Text, botID, "", -1))
+	attribution := fmt.Sprintf("Originally posted by %s in <#%s> (<%s|view original>). Published by <@%s>.", postedBy, source.ChannelID, permalink, publisher)
+	ts, err := h.postComposedUpdate(channelID, "", text, attribution, ComposedUpdate{Author: author})
+	if err != nil {
+		log.Printf("Could not publish message: %s\n", err)
+		h.notifyUser(source.ChannelID, publisher, fmt.Sprintf("Sorry, I couldn't post that to <#%s>. Is the status page bot in there?", channelID))
+		return
+	}
+
+	if config().Drafts {
+		h.saveDraft(channelID, ts, publisher)
+	}
+	h.requestApproval(channelID, ts, publisher, "")
+
+	forwardChannelName, err := h.resolveChannelName(h.workspace.ForwardChannelID)
+	if err != nil {
+		log.Printf("Could not resolve channel name: %s\n", err)
+	}
+	blocks := CreateUpdateResponseMsg(forwardChannelName, publisher)
+	_, _, err = h.slackSocket.PostMessage(channelID, slack.MsgOptionTS(ts), slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		log.Printf("Error posting prompt: %s\n", err)
+	}
+	h.postPreview(channelID, ts)
+	h.shouldUpdate = true
+}
+
+// The name an integration posted a message under
+func integrationName(message slack.Message) string {
+	if message.BotProfile != nil && message.BotProfile.Name != "" {
+		return message.BotProfile.Name
+	}
+	if message.Username != "" {
+		return message.Username
+	}
+	return "an integration"
+}
+
+// Buttons in the App Home all act on the update whose ID is in their value
+func (h *CSPSlackEvtHandler) handleHomeInteraction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	updateID := action.Value
+	channelID, ts := parseSlackUpdateID(updateID)
+	if !h.isStatusChannel(channelID) {
+		return
+	}
+	log.Printf("App Home action %s on %s\n", action.ActionID, updateID)
+	if !h.canPublish(callback.User.ID) {
+		h.notifyDenied("", callback.User.ID)
+		return
+	}
 
-	// Clear any old reactions
+	var err error
 	switch action.ActionID {
-	case CSPSetOK, CSPSetWarn, CSPSetError:
-		h.clearReactions(
-			callback.Container.ThreadTs,
-			[]string{
-				config.StatusOKEmoji,
-				config.StatusWarnEmoji,
-				config.StatusErrorEmoji,
-			},
-		)
+	case CSPHomeResolve:
+		err = h.resolveUpdate(updateID)
+	case CSPHomeUnpin:
+		err = h.slackSocket.RemovePin(channelID, slack.NewRefToMessage(channelID, ts))
+	case CSPHomeFollowUp:
+		_, err = h.slackAPI.OpenView(callback.TriggerID, CreateFollowUpModal(updateID))
+		// Nothing has changed yet
+		if err != nil {
+			log.Println(err)
+		}
+		return
+	default:
+		severity := strings.TrimPrefix(action.ActionID, CSPHomeSetSeverityPrefix)
+		if _, ok := severityLevel(severity); !ok {
+			// Stale buttons from before a reload. Refreshing the Home fixes them.
+			err = fmt.Errorf("unknown severity level '%s'", severity)
+			break
+		}
+		h.requestApproval(channelID, ts, callback.User.ID, severity)
+		err = h.setUpdateSeverity(channelID, ts, severityEmoji(severity))
 	}
+	if err != nil {
+		log.Println(err)
+	}
+	h.shouldUpdate = true
+}
+
+// Marks an update resolved and OK, all in one go
+func (h *CSPSlackEvtHandler) resolveUpdate(updateID string) error {
+	channelID, ts := parseSlackUpdateID(updateID)
+	err := store.addIncidentState(updateID, StateResolved, time.Now())
+	if err != nil {
+		return err
+	}
+	return h.setUpdateSeverity(channelID, ts, severityEmoji(resolvedSeverity()))
+}
+
+// How long the snooze button on a reminder keeps an update out of them
+const reminderSnooze = 24 * time.Hour
 
-	// Add the reaction we want
+// Buttons on the reminder message. Once one's been handled, its buttons get
+// swapped for a note, so nobody handles it twice.
+func (h *CSPSlackEvtHandler) handleReminderInteraction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	updateID := action.Value
+	channelID, ts := parseSlackUpdateID(updateID)
+	if !h.isStatusChannel(channelID) {
+		return
+	}
+	log.Printf("Reminder action %s on %s\n", action.ActionID, updateID)
+	user := callback.User.ID
+	if !h.canPublish(user) {
+		h.notifyDenied(callback.Channel.ID, user)
+		return
+	}
+
+	var err error
+	var note string
 	switch action.ActionID {
-	case CSPSetOK:
-		err := h.slackSocket.AddReaction(config.StatusOKEmoji, itemRef)
+	case CSPReminderUnpin:
+		err = h.slackSocket.RemovePin(channelID, slack.NewRefToMessage(channelID, ts))
+		note = fmt.Sprintf(":pushpin: Unpinned by <@%s>", user)
+	case CSPReminderResolve:
+		err = h.resolveUpdate(updateID)
+		note = fmt.Sprintf(":white_check_mark: Marked resolved by <@%s>", user)
+	case CSPReminderSnooze:
+		until := time.Now().Add(reminderSnooze)
+		err = store.snoozeReminders(updateID, until)
+		note = fmt.Sprintf(":zzz: Snoozed by <@%s> until %s", user, timeToHumanTime(until))
+	}
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	blocks := replaceReminderActions(callback.Message.Blocks.BlockSet, action.BlockID, note)
+	_, _, _, err = h.slackSocket.UpdateMessage(callback.Channel.ID, callback.Message.Timestamp, slack.MsgOptionText(callback.Message.Text, false), slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		log.Println(err)
+	}
+	if action.ActionID != CSPReminderSnooze {
+		h.shouldUpdate = true
+	}
+}
+
+// Posts a follow-up from the App Home into the update's thread, where it'll
+// show up on the page like any other follow-up.
+func (h *CSPSlackEvtHandler) handleFollowUpSubmission(callback slack.InteractionCallback) {
+	updateID := callback.View.PrivateMetadata
+	channelID, threadTs := parseSlackUpdateID(updateID)
+	text := callback.View.State.Values[CSPComposeMessage][CSPComposeMessage].Value
+
+	ts, err := h.postComposedUpdate(channelID, threadTs, text, fmt.Sprintf("Posted for <@%s>", callback.User.ID), ComposedUpdate{Author: callback.User.ID})
+	if err != nil {
+		log.Printf("Could not post follow-up: %s\n", err)
+		return
+	}
+	if state, ok := parseIncidentState(text); ok {
+		err = store.addIncidentState(updateID, state, slackTSToTime(ts))
 		if err != nil {
-			// Handle the error
-			h.slackSocket.Debugf("Error adding reaction: %v", err)
+			log.Println(err)
+		}
+	}
+	h.shouldUpdate = true
+}
+
+// Lets someone know they aren't one of the people who can publish. Without a
+// channel to whisper in, it goes to their DMs.
+func (h *CSPSlackEvtHandler) notifyDenied(channelID string, userID string) {
+	log.Printf("%s isn't allowed to publish\n", userID)
+	h.notifyUser(channelID, userID, "Sorry, you aren't allowed to publish to the status page. Ask an admin to add you to the list.")
+}
+
+// Tells one person something, quietly in the channel they're in if we can,
+// and in a DM if we can't
+func (h *CSPSlackEvtHandler) notifyUser(channelID string, userID string, text string) {
+	if channelID != "" {
+		_, err := h.slackSocket.PostEphemeral(channelID, userID, slack.MsgOptionText(text, false))
+		if err == nil {
+			return
 		}
-	case CSPSetWarn:
-		err := h.slackSocket.AddReaction(config.StatusWarnEmoji, itemRef)
+		log.Println(err)
+	}
+	_, _, err := h.slackSocket.PostMessage(userID, slack.MsgOptionText(text, false))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// A second publisher signing off on an update, which puts it on the page
+func (h *CSPSlackEvtHandler) handleApproveInteraction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	updateID := action.Value
+	approver := callback.User.ID
+	if !h.canPublish(approver) {
+		h.notifyDenied(callback.Channel.ID, approver)
+		return
+	}
+	approval, ok := store.approval(updateID)
+	if !ok || !approval.pending() {
+		return
+	}
+	if approver == approval.RequestedBy {
+		_, err := h.slackSocket.PostEphemeral(callback.Channel.ID, approver, slack.MsgOptionText("You can't approve your own update. Ask someone else to take a look.", false))
 		if err != nil {
-			// Handle the error
-			h.slackSocket.Debugf("Error adding reaction: %v", err)
+			log.Println(err)
 		}
-	case CSPSetError:
-		err := h.slackSocket.AddReaction(config.StatusErrorEmoji, itemRef)
+		return
+	}
+
+	approval.ApprovedBy = approver
+	approval.ApprovedAt = time.Now()
+	err := store.setApproval(updateID, approval)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf("%s approved %s\n", approver, updateID)
+
+	channelID, _ := parseSlackUpdateID(updateID)
+	_, _, _, err = h.slackSocket.UpdateMessage(channelID, approval.MessageID, slack.MsgOptionBlocks(CreateApprovedMsg(approval.RequestedBy, approver)...))
+	if err != nil {
+		log.Println(err)
+	}
+	h.shouldUpdate = true
+}
+
+// Keeps a new update off the page until someone publishes it
+func (h *CSPSlackEvtHandler) saveDraft(channelID string, timestamp string, author string) {
+	err := store.setDraft(slackUpdateID(channelID, timestamp), Draft{Author: author, CreatedAt: time.Now()})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// Somebody's happy with how a draft looks, so put it on the page
+func (h *CSPSlackEvtHandler) handlePublishDraftInteraction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	updateID := action.Value
+	publisher := callback.User.ID
+	if !h.canPublish(publisher) {
+		h.notifyDenied(callback.Channel.ID, publisher)
+		return
+	}
+	draft, ok := store.draft(updateID)
+	if !ok || !draft.pending() {
+		return
+	}
+
+	draft.PublishedBy = publisher
+	draft.PublishedAt = time.Now()
+	err := store.setDraft(updateID, draft)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf("%s published draft %s\n", publisher, updateID)
+
+	channelID, ts := parseSlackUpdateID(updateID)
+	blocks, err := h.previewMsg(channelID, ts)
+	if err != nil {
+		log.Printf("Could not build preview: %s\n", err)
+	} else {
+		_, _, _, err = h.slackSocket.UpdateMessage(callback.Channel.ID, callback.Container.MessageTs, slack.MsgOptionBlocks(blocks...))
 		if err != nil {
-			// Handle the error
-			h.slackSocket.Debugf("Error adding reaction: %v", err)
+			log.Println(err)
 		}
-	case CSPCancel:
 	}
-	_, _, err := h.slackSocket.DeleteMessage(config.SlackStatusChannelID, callback.Container.MessageTs)
+	h.shouldUpdate = true
+}
+
+func (h *CSPSlackEvtHandler) openMaintenanceModal(triggerID string) {
+	_, err := h.slackAPI.OpenView(triggerID, CreateMaintenanceModal())
+	if err != nil {
+		log.Printf("Could not open maintenance modal: %s\n", err)
+	}
+}
+
+// Schedules maintenance from the modal. Anything wrong with it goes back to
+// the modal instead, so nobody has to fill it out again.
+func (h *CSPSlackEvtHandler) handleMaintenanceSubmission(callback slack.InteractionCallback) *slack.ViewSubmissionResponse {
+	values := callback.View.State.Values
+	maintenance := Maintenance{
+		Title:       values[CSPMaintenanceTitle][CSPMaintenanceTitle].Value,
+		Description: values[CSPMaintenanceDescription][CSPMaintenanceDescription].Value,
+		Components:  selectedValues(callback.View.State, CSPComponentsBlock, CSPComponentsBlock),
+		CreatedBy:   callback.User.Name,
+	}
+	if start := values[CSPMaintenanceStart][CSPMaintenanceStart].SelectedDateTime; start != 0 {
+		maintenance.Start = time.Unix(start, 0)
+	}
+	if end := values[CSPMaintenanceEnd][CSPMaintenanceEnd].SelectedDateTime; end != 0 {
+		maintenance.End = time.Unix(end, 0)
+	}
+	if user, err := h.slackSocket.GetUserInfo(callback.User.ID); err == nil {
+		maintenance.CreatedBy = user.RealName
+	}
+
+	err := maintenance.validate(time.Now())
+	if err != nil {
+		return slack.NewErrorsViewSubmissionResponse(map[string]string{maintenanceErrorBlock(err): err.Error()})
+	}
+	err = store.setMaintenance(maintenance)
 	if err != nil {
 		log.Println(err)
+		return nil
 	}
+	log.Printf("%s scheduled maintenance '%s'\n", callback.User.ID, maintenance.Title)
+	advanceMaintenance(time.Now())
+	h.shouldUpdate = true
+	return nil
+}
+
+// Which field in the maintenance form a problem with it belongs under
+func maintenanceErrorBlock(err error) string {
+	switch {
+	case errors.Is(err, errMaintenanceTitle):
+		return CSPMaintenanceTitle
+	case errors.Is(err, errMaintenanceStart):
+		return CSPMaintenanceStart
+	case errors.Is(err, errMaintenanceComponent):
+		return CSPComponentsBlock
+	}
+	return CSPMaintenanceEnd
+}
+
+func (h *CSPSlackEvtHandler) handleCancelMaintenanceInteraction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	if !h.canPublish(callback.User.ID) {
+		h.notifyDenied("", callback.User.ID)
+		return
+	}
+	_, err := cancelMaintenance(action.Value)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	h.shouldUpdate = true
+}
+
+// Someone picked when the update the prompt is under should come down
+func (h *CSPSlackEvtHandler) handlePinExpiryInteraction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	if !h.canPublish(callback.User.ID) {
+		h.notifyDenied(callback.Channel.ID, callback.User.ID)
+		return
+	}
+	updateID := slackUpdateID(callback.Channel.ID, callback.Container.ThreadTs)
+	err := choosePinExpiry(updateID, action.SelectedOption.Value, time.Now())
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	log.Printf("%s set %s to unpin: %s\n", callback.User.ID, updateID, action.SelectedOption.Value)
+	h.shouldUpdate = true
 }
@@ -9,16 +9,11 @@ import (
 	"strings"
 	"time"
 
-	"github.com/gomarkdown/markdown"
-	"github.com/microcosm-cc/bluemonday"
 	"github.com/slack-go/slack"
 )
 
 func MrkdwnToHTML(message string) (formattedHtml template.HTML) {
-	md := mrkdwnToMarkdown(message)
-	maybeUnsafeHTML := markdown.ToHTML([]byte(md), nil, nil)
-	blueMondayHtml := bluemonday.UGCPolicy().SanitizeBytes(maybeUnsafeHTML)
-	templatedHTML := template.HTML(blueMondayHtml)
+	templatedHTML := MarkdownToHTML(mrkdwnToMarkdown(message))
 
 	// Slack, for some insane reason, gives us messages with < formatted as &lt;,
 	// so we need to correct that.
@@ -80,43 +75,48 @@ func mrkdwnToMarkdown(input string) string {
 }
 
 func slackTSToTime(slackTimestamp string) (slackTime time.Time) {
-	// Convert the Slack timestamp to a Unix timestamp (float64)
-	slackUnixTimestamp, err := strconv.ParseFloat(strings.Split(slackTimestamp, ".")[0], 64)
+	// Slack timestamps are seconds and microseconds, like 1709294400.000100.
+	// Parse them separately so floats don't eat the precision.
+	seconds, fraction, _ := strings.Cut(slackTimestamp, ".")
+	unixSeconds, err := strconv.ParseInt(seconds, 10, 64)
 	if err != nil {
 		fmt.Println("Error parsing Slack timestamp:", err)
 		return
 	}
-
-	// Create a time.Time object from the Unix timestamp (assuming UTC time zone)
-	slackTime = time.Unix(int64(slackUnixTimestamp), 0)
-	return slackTime
-}
-
-// Converts the timestamp from a message into a human-readable format.
-func slackTSToHumanTime(slackTimestamp string) (hrt string) {
-	slackTime := slackTSToTime(slackTimestamp)
-
-	// Convert to a specific time zone (e.g., "America/New_York")
-	location, err := time.LoadLocation("America/New_York")
-	if err != nil {
-		fmt.Println("Error loading location:", err)
-		return
+	var nanoseconds int64
+	if fraction != "" {
+		fraction = (fraction + "000000000")[:9]
+		nanoseconds, err = strconv.ParseInt(fraction, 10, 64)
+		if err != nil {
+			fmt.Println("Error parsing Slack timestamp:", err)
+			return
+		}
 	}
+	return time.Unix(unixSeconds, nanoseconds)
+}
 
-	slackTimeInLocation := slackTime.In(location)
-
-	// Format the time as a human-readable string
-	humanReadableTimestamp := slackTimeInLocation.Format("2006-01-02 15:04:05 MST")
-
-	return humanReadableTimestamp
+// Slack shows these in whatever time zone the reader is in
+func slackDate(t time.Time) string {
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>", t.Unix(), timeToHumanTime(t))
 }
 
 // Function to build the message the bot sends in response to being pinged with
 // a new status update.
 func CreateUpdateResponseMsg(channelName string, user string) (blocks []slack.Block) {
+	c := config()
+	warning := "*Warning: this alert is live immediately!*"
+	switch c.ApprovalMode {
+	case ApprovalAll:
+		warning = "It won't go live until someone else approves it."
+	case ApprovalCritical:
+		warning = "*Warning: this alert is live immediately!* Critical alerts need someone else to approve them first."
+	}
+	if c.Drafts {
+		warning = "It's a draft until someone publishes it from the preview below."
+	}
 	blocks = []slack.Block{
 		slack.NewSectionBlock(
-			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("<@%s> I see you have posted a new message to the support page. What kind of alert is this? *Warning: this alert is live immediately!*", user), false, false),
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("<@%s> I see you have posted a new message to the support page. What kind of alert is this? %s", user, warning), false, false),
 			nil,
 			nil,
 		),
@@ -145,48 +145,146 @@ func CreateUpdateResponseMsg(channelName string, user string) (blocks []slack.Bl
 				),
 			),
 		),
-		slack.NewActionBlock(
+	}
+
+	// One button per severity level, worst first
+	var severityButtons []slack.BlockElement
+	for _, level := range severityLevelsWorstFirst() {
+		severityButtons = append(severityButtons, slack.NewButtonBlockElement(
+			CSPSetSeverityPrefix+level.Name,
+			level.Name,
+			slack.NewTextBlockObject("plain_text", level.ButtonLabel(), true, false),
+		))
+	}
+	severityButtons = append(severityButtons, slack.NewButtonBlockElement(
+		CSPCancel,
+		CSPCancel,
+		slack.NewTextBlockObject("plain_text", "❌Close", true, false),
+	))
+	blocks = append(blocks, slack.NewActionBlock("", severityButtons...))
+
+	// Where we're at with it. These can be changed later by replying in the
+	// thread.
+	var stateButtons []slack.BlockElement
+	for _, state := range incidentStates {
+		stateButtons = append(stateButtons, slack.NewButtonBlockElement(
+			CSPSetStatePrefix+state,
+			state,
+			slack.NewTextBlockObject("plain_text", incidentStateLabel(state), false, false),
+		))
+	}
+	blocks = append(blocks,
+		slack.NewContextBlock(
+			"",
+			slack.NewTextBlockObject(slack.MarkdownType, "Incident state (optional). Reply in the thread starting with one of these to change it later.", false, false),
+		),
+		slack.NewActionBlock("", stateButtons...),
+		slack.NewActionBlock("", pinExpirySelect()),
+	)
+
+	// Let people say which parts of the network this is about, if we have
+	// any parts configured.
+	if len(c.Components) > 0 {
+		blocks = append(blocks, slack.NewActionBlock(
 			"",
 			slack.NewButtonBlockElement(
-				CSPSetError,
-				CSPSetError,
-				slack.NewTextBlockObject("plain_text", "🔥 Critical", true, false),
-			),
-			slack.NewButtonBlockElement(
-				CSPSetWarn,
-				CSPSetWarn,
-				slack.NewTextBlockObject("plain_text", "⚠️ Warning", true, false),
-			),
-			slack.NewButtonBlockElement(
-				CSPSetOK,
-				CSPSetOK,
-				slack.NewTextBlockObject("plain_text", "✅ OK/Info", true, false),
-			),
-			slack.NewButtonBlockElement(
-				CSPCancel,
-				CSPCancel,
-				slack.NewTextBlockObject("plain_text", "❌Close", true, false),
+				CSPTagComponents,
+				CSPTagComponents,
+				slack.NewTextBlockObject("plain_text", "🏷️ Tag components", true, false),
 			),
-		),
+		))
 	}
 	return blocks
 }
 
-func GetPinnedMessageStatus(reactions []slack.ItemReaction) string {
+// Lets people pick when a pinned update should come down on its own
+func pinExpirySelect() *slack.SelectBlockElement {
+	var options []*slack.OptionBlockObject
+	for _, choice := range expiryChoices {
+		options = append(options, slack.NewOptionBlockObject(
+			choice.Value,
+			slack.NewTextBlockObject(slack.PlainTextType, choice.Label, false, false),
+			nil,
+		))
+	}
+	return slack.NewOptionsSelectBlockElement(
+		slack.OptTypeStatic,
+		slack.NewTextBlockObject(slack.PlainTextType, "Unpin automatically…", false, false),
+		CSPPinExpiry,
+		options...,
+	)
+}
+
+func componentOptions(names []string) (options []*slack.OptionBlockObject) {
+	for _, name := range names {
+		options = append(options, slack.NewOptionBlockObject(
+			name,
+			slack.NewTextBlockObject(slack.PlainTextType, name, false, false),
+			nil,
+		))
+	}
+	return options
+}
+
+// A multi-select with every configured component in it
+func componentSelectElement(actionID string, selected []string) *slack.MultiSelectBlockElement {
+	var names []string
+	for _, component := range config().Components {
+		names = append(names, component.Name)
+	}
+	element := slack.NewOptionsMultiSelectBlockElement(
+		slack.MultiOptTypeStatic,
+		slack.NewTextBlockObject(slack.PlainTextType, "Pick components", false, false),
+		actionID,
+		componentOptions(names)...,
+	)
+	if len(selected) > 0 {
+		element.InitialOptions = componentOptions(selected)
+	}
+	return element
+}
+
+// The modal for picking which components an update is about
+func CreateComponentsModal(updateID string, selected []string) slack.ModalViewRequest {
+	input := slack.NewInputBlock(
+		CSPComponentsBlock,
+		slack.NewTextBlockObject(slack.PlainTextType, "Affected components", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "You can also mention components with #hashtags in your message.", false, false),
+		componentSelectElement(CSPComponentsBlock, selected),
+	)
+	input.Optional = true
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      CSPComponentsModal,
+		PrivateMetadata: updateID,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Tag components", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Save", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks:          slack.Blocks{BlockSet: []slack.Block{input}},
+	}
+}
+
+// Reads the options picked in a multi-select out of a submitted view
+func selectedValues(state *slack.ViewState, blockID string, actionID string) (values []string) {
+	if state == nil {
+		return nil
+	}
+	for _, option := range state.Values[blockID][actionID].SelectedOptions {
+		values = append(values, option.Value)
+	}
+	return values
+}
+
+func GetPinnedMessageStatus(reactions []slack.ItemReaction, botID string) string {
 	for _, reaction := range reactions {
 		// Only take action on our reactions
-		if botReaction := stringInSlice(reaction.Users, config.SlackBotID); !botReaction {
+		if botReaction := stringInSlice(reaction.Users, botID); !botReaction {
 			continue
 		}
 
 		// Use the first reaction sent by the bot that we find
-		switch reaction.Name {
-		case config.StatusOKEmoji:
-			return config.StatusOKEmoji
-		case config.StatusWarnEmoji:
-			return config.StatusWarnEmoji
-		case config.StatusErrorEmoji:
-			return config.StatusErrorEmoji
+		if isRelevantReaction(reaction.Name) {
+			return reaction.Name
 		}
 	}
 	return ""
@@ -194,10 +292,546 @@ func GetPinnedMessageStatus(reactions []slack.ItemReaction) string {
 
 // Ignore messages that don't mention us. Also, ignore messages that
 // mention us but are empty!
-func botActionablyMentioned(message string) bool {
-	botID := fmt.Sprintf("<@%s>", config.SlackBotID)
-	if !strings.Contains(message, botID) || message == botID {
+func botActionablyMentioned(message string, botID string) bool {
+	botMention := fmt.Sprintf("<@%s>", botID)
+	if !strings.Contains(message, botMention) || message == botMention {
 		return false
 	}
 	return true
 }
+
+// Updates are identified by the channel and timestamp of their message
+func slackUpdateID(channelID string, timestamp string) string {
+	return channelID + "/" + timestamp
+}
+
+// Splits an update ID back into its channel and timestamp
+func parseSlackUpdateID(updateID string) (channelID string, timestamp string) {
+	channelID, timestamp, _ = strings.Cut(updateID, "/")
+	return channelID, timestamp
+}
+
+// One option per status channel, labelled with its name and component
+func statusChannelOptions(channels []StatusChannel, names map[string]string) (options []*slack.OptionBlockObject) {
+	for _, channel := range channels {
+		label := "#" + names[channel.ID]
+		if channel.Component != "" {
+			label += " (" + channel.Component + ")"
+		}
+		options = append(options, slack.NewOptionBlockObject(
+			channel.ID,
+			slack.NewTextBlockObject(slack.PlainTextType, label, false, false),
+			nil,
+		))
+	}
+	return options
+}
+
+// A required pick of which status channel something should go to
+func statusChannelInput(channels []StatusChannel, names map[string]string, selectedChannel string) *slack.InputBlock {
+	channelOptions := statusChannelOptions(channels, names)
+	channelSelect := slack.NewOptionsSelectBlockElement(
+		slack.OptTypeStatic,
+		slack.NewTextBlockObject(slack.PlainTextType, "Pick a channel", false, false),
+		CSPComposeChannel,
+		channelOptions...,
+	)
+	for _, option := range channelOptions {
+		if option.Value == selectedChannel {
+			channelSelect.InitialOption = option
+		}
+	}
+	return slack.NewInputBlock(
+		CSPComposeChannel,
+		slack.NewTextBlockObject(slack.PlainTextType, "Status channel", false, false),
+		nil,
+		channelSelect,
+	)
+}
+
+// Asks which status channel a message from elsewhere should be published to
+func CreatePublishModal(sourceID string, channels []StatusChannel, names map[string]string) slack.ModalViewRequest {
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      CSPPublishModal,
+		PrivateMetadata: sourceID,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Publish to status page", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Publish", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks:          slack.Blocks{BlockSet: []slack.Block{statusChannelInput(channels, names, "")}},
+	}
+}
+
+// The modal for writing a status update from scratch. The bot posts it to the
+// status channel for you once you're done.
+func CreateComposeModal(channels []StatusChannel, names map[string]string, selectedChannel string, forwardChannelName string) slack.ModalViewRequest {
+	c := config()
+	message := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "What's going on?", false, false),
+		CSPComposeMessage,
+	)
+	message.Multiline = true
+	blocks := []slack.Block{
+		slack.NewInputBlock(
+			CSPComposeMessage,
+			slack.NewTextBlockObject(slack.PlainTextType, "Message", false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, "Slack formatting works here, and so do #component hashtags.", false, false),
+			message,
+		),
+	}
+
+	// No point asking if there's only one place it could go
+	if len(channels) > 1 {
+		blocks = append(blocks, statusChannelInput(channels, names, selectedChannel))
+	}
+
+	severity := slack.NewInputBlock(
+		CSPComposeSeverity,
+		slack.NewTextBlockObject(slack.PlainTextType, "Severity", false, false),
+		nil,
+		slack.NewRadioButtonsBlockElement(CSPComposeSeverity, severityOptions()...),
+	)
+	severity.Optional = true
+	blocks = append(blocks, severity)
+
+	if len(c.Components) > 0 {
+		components := slack.NewInputBlock(
+			CSPComponentsBlock,
+			slack.NewTextBlockObject(slack.PlainTextType, "Affected components", false, false),
+			nil,
+			componentSelectElement(CSPComponentsBlock, nil),
+		)
+		components.Optional = true
+		blocks = append(blocks, components)
+	}
+
+	options := []*slack.OptionBlockObject{
+		slack.NewOptionBlockObject(CSPPin, slack.NewTextBlockObject(slack.PlainTextType, "Pin this message to the status page", false, false), nil),
+	}
+	if forwardChannelName != "" {
+		options = append(options, slack.NewOptionBlockObject(
+			CSPForward,
+			slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Forward message to the #%s channel", forwardChannelName), false, false),
+			nil,
+		))
+	}
+	optionsInput := slack.NewInputBlock(
+		CSPComposeOptions,
+		slack.NewTextBlockObject(slack.PlainTextType, "Options", false, false),
+		nil,
+		slack.NewCheckboxGroupsBlockElement(CSPComposeOptions, options...),
+	)
+	optionsInput.Optional = true
+	blocks = append(blocks, optionsInput)
+
+	expiry := slack.NewInputBlock(
+		CSPPinExpiry,
+		slack.NewTextBlockObject(slack.PlainTextType, "Unpin automatically", false, false),
+		nil,
+		pinExpirySelect(),
+	)
+	expiry.Optional = true
+	blocks = append(blocks, expiry)
+
+	resolution := slack.NewInputBlock(
+		CSPComposeResolution,
+		slack.NewTextBlockObject(slack.PlainTextType, "Expected resolution", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Leave blank if you don't know yet.", false, false),
+		slack.NewDateTimePickerBlockElement(CSPComposeResolution),
+	)
+	resolution.Optional = true
+	blocks = append(blocks, resolution)
+
+	public := slack.NewOptionBlockObject(
+		CSPVisibilityPublic,
+		slack.NewTextBlockObject(slack.PlainTextType, "Public", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Show it on the status page", false, false),
+	)
+	draft := slack.NewOptionBlockObject(
+		CSPVisibilityDraft,
+		slack.NewTextBlockObject(slack.PlainTextType, "Draft", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Keep it off the status page until someone publishes it", false, false),
+	)
+	visibility := slack.NewRadioButtonsBlockElement(
+		CSPComposeVisibility,
+		public,
+		draft,
+		slack.NewOptionBlockObject(
+			CSPVisibilityInternal,
+			slack.NewTextBlockObject(slack.PlainTextType, "Internal", false, false),
+			slack.NewTextBlockObject(slack.PlainTextType, "Only post it in Slack", false, false),
+		),
+	)
+	visibility.InitialOption = public
+	if c.Drafts {
+		visibility.InitialOption = draft
+	}
+	blocks = append(blocks, slack.NewInputBlock(
+		CSPComposeVisibility,
+		slack.NewTextBlockObject(slack.PlainTextType, "Visibility", false, false),
+		nil,
+		visibility,
+	))
+
+	return slack.ModalViewRequest{
+		Type:       slack.VTModal,
+		CallbackID: CSPComposeModal,
+		Title:      slack.NewTextBlockObject(slack.PlainTextType, "New status update", false, false),
+		Submit:     slack.NewTextBlockObject(slack.PlainTextType, "Post", false, false),
+		Close:      slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks:     slack.Blocks{BlockSet: blocks},
+	}
+}
+
+// What the App Home needs to know about each pinned update
+type homeUpdate struct {
+	ID        string
+	Text      string
+	Emoji     string
+	Age       string
+	State     string
+	Permalink string
+	Pending   bool
+	Draft     bool
+	Expires   string
+}
+
+// Most of a message is plenty to recognize it by
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
+
+// Slack won't take more than 100 blocks in a Home tab. The 20 updates we show
+// take up to four each, and the headers around everything take five more.
+const maxHomeMaintenance = 10
+
+// The App Home tab, where admins can keep track of everything that's pinned
+// without scrolling through the channel for it. Everybody else just gets to
+// look. Past maxHomeMaintenance, the rest of the maintenance just gets counted.
+func CreateHomeView(updates []homeUpdate, maintenance []Maintenance, canManage bool) slack.HomeTabViewRequest {
+	moreMaintenance := 0
+	if len(maintenance) > maxHomeMaintenance {
+		moreMaintenance = len(maintenance) - maxHomeMaintenance
+		maintenance = maintenance[:maxHomeMaintenance]
+	}
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "Status Page", false, false)),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%d pinned update(s). Run `%s` to post a new one.", len(updates), CSPComposeCommand), false, false)),
+	}
+	if len(updates) == 0 {
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, ":sparkles: Nothing is pinned. The page says everything is fine.", false, false),
+			nil,
+			nil,
+		))
+	}
+
+	for _, update := range updates {
+		emoji := "•"
+		if update.Emoji != "" {
+			emoji = fmt.Sprintf(":%s:", update.Emoji)
+		}
+		details := fmt.Sprintf("Posted %s ago", update.Age)
+		if update.State != "" {
+			details += " · " + incidentStateLabel(update.State)
+		}
+		if update.Pending {
+			details += " · :hourglass: Awaiting approval"
+		}
+		if update.Draft {
+			details += " · :memo: Draft"
+		}
+		if update.Expires != "" {
+			details += fmt.Sprintf(" · Unpins in %s", update.Expires)
+		}
+		if update.Permalink != "" {
+			details += fmt.Sprintf(" · <%s|View in channel>", update.Permalink)
+		}
+
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s %s", emoji, truncateText(update.Text, 500)), false, false),
+				nil,
+				nil,
+			),
+			slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, details, false, false)),
+		)
+		if !canManage {
+			continue
+		}
+		var buttons []slack.BlockElement
+		for _, level := range severityLevelsWorstFirst() {
+			buttons = append(buttons, slack.NewButtonBlockElement(CSPHomeSetSeverityPrefix+level.Name, update.ID, slack.NewTextBlockObject(slack.PlainTextType, level.ButtonLabel(), true, false)))
+		}
+		buttons = append(buttons,
+			slack.NewButtonBlockElement(CSPHomeResolve, update.ID, slack.NewTextBlockObject(slack.PlainTextType, "Resolve", false, false)).WithStyle(slack.StylePrimary),
+			slack.NewButtonBlockElement(CSPHomeFollowUp, update.ID, slack.NewTextBlockObject(slack.PlainTextType, "Post follow-up", false, false)),
+			slack.NewButtonBlockElement(CSPHomeUnpin, update.ID, slack.NewTextBlockObject(slack.PlainTextType, "Unpin", false, false)).WithStyle(slack.StyleDanger),
+		)
+		blocks = append(blocks, slack.NewActionBlock("", buttons...))
+	}
+
+	if len(maintenance) > 0 {
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "Upcoming maintenance", false, false)),
+		)
+	}
+	for _, m := range maintenance {
+		var accessory *slack.Accessory
+		if canManage {
+			accessory = slack.NewAccessory(slack.NewButtonBlockElement(CSPMaintenanceCancel, m.ID, slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false)).WithStyle(slack.StyleDanger))
+		}
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*%s*\n%s until %s", slackEscaper.Replace(m.Title), m.StartTimeStamp(), m.EndTimeStamp()), false, false),
+			nil,
+			accessory,
+		))
+	}
+	if moreMaintenance > 0 {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("+%d more, see the status page for the rest.", moreMaintenance), false, false)))
+	}
+
+	return slack.HomeTabViewRequest{
+		Type:   slack.VTHomeTab,
+		Blocks: slack.Blocks{BlockSet: blocks},
+	}
+}
+
+// The modal for posting a follow-up from the App Home
+func CreateFollowUpModal(updateID string) slack.ModalViewRequest {
+	message := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "Any news?", false, false),
+		CSPComposeMessage,
+	)
+	message.Multiline = true
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      CSPFollowUpModal,
+		PrivateMetadata: updateID,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Post follow-up", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Post", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(
+				CSPComposeMessage,
+				slack.NewTextBlockObject(slack.PlainTextType, "Follow-up", false, false),
+				slack.NewTextBlockObject(slack.PlainTextType, "Shows up under the update on the page. Start with Identified, Monitoring or Resolved to move the incident along.", false, false),
+				message,
+			),
+		}},
+	}
+}
+
+// Asks for a second publisher to sign off on an update
+func CreateApprovalRequestMsg(updateID string, requester string) []slack.Block {
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf(":hourglass: <@%s> wants to put this on the status page. Someone else needs to approve it before it goes live.", requester), false, false),
+			nil,
+			nil,
+		),
+		slack.NewActionBlock(
+			"",
+			slack.NewButtonBlockElement(CSPApprove, updateID, slack.NewTextBlockObject(slack.PlainTextType, "Approve", false, false)).WithStyle(slack.StylePrimary),
+		),
+	}
+}
+
+// What the approval request turns into once it's been approved
+func CreateApprovedMsg(requester string, approver string) []slack.Block {
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf(":white_check_mark: Requested by <@%s>, approved by <@%s>. It's live.", requester, approver), false, false),
+			nil,
+			nil,
+		),
+	}
+}
+
+// Slack won't take more than 50 blocks in a message. Each reminder takes
+// three, and there are four more around them.
+const maxReminders = 15
+
+// Lists the updates that have been pinned a while, each with buttons to take
+// care of it. The buttons' block is named after the update, so it can be
+// swapped out once someone has. Past maxReminders, the rest just get counted.
+func CreateReminderMsg(intro string, reminders []ReminderInfo) []slack.Block {
+	more := 0
+	if len(reminders) > maxReminders {
+		more = len(reminders) - maxReminders
+		reminders = reminders[:maxReminders]
+	}
+	blocks := []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, intro, false, false),
+			nil,
+			nil,
+		),
+	}
+	for _, m := range reminders {
+		parsedStatus := "•"
+		if m.status != "" {
+			parsedStatus = fmt.Sprintf(":%s:", m.status)
+		}
+		blocks = append(blocks,
+			slack.NewDividerBlock(),
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s <@%s> Since %s · <%s|View message>", parsedStatus, m.userID, slackDate(m.at), m.link), false, false),
+				nil,
+				nil,
+			),
+			slack.NewActionBlock(
+				m.updateID,
+				slack.NewButtonBlockElement(CSPReminderUnpin, m.updateID, slack.NewTextBlockObject(slack.PlainTextType, "Unpin", false, false)).WithStyle(slack.StyleDanger),
+				slack.NewButtonBlockElement(CSPReminderResolve, m.updateID, slack.NewTextBlockObject(slack.PlainTextType, "Mark resolved", false, false)).WithStyle(slack.StylePrimary),
+				slack.NewButtonBlockElement(CSPReminderSnooze, m.updateID, slack.NewTextBlockObject(slack.PlainTextType, "Snooze 24h", false, false)),
+			),
+		)
+	}
+	if more > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("+%d more, too many to list here.", more), false, false),
+			nil,
+			nil,
+		))
+	}
+	blocks = append(blocks,
+		slack.NewDividerBlock(),
+		slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, "It might be time to unpin them if they are no longer relevant.", false, false)),
+	)
+	return blocks
+}
+
+// Swaps an update's buttons on the reminder for a note saying what happened
+// to it
+func replaceReminderActions(blocks []slack.Block, blockID string, note string) []slack.Block {
+	replaced := make([]slack.Block, 0, len(blocks))
+	for _, block := range blocks {
+		if actions, ok := block.(*slack.ActionBlock); ok && actions.BlockID == blockID {
+			block = slack.NewContextBlock(blockID, slack.NewTextBlockObject(slack.MarkdownType, note, false, false))
+		}
+		replaced = append(replaced, block)
+	}
+	return replaced
+}
+
+// The severity levels as options to pick from, worst first
+func severityOptions() (options []*slack.OptionBlockObject) {
+	for _, level := range severityLevelsWorstFirst() {
+		options = append(options, slack.NewOptionBlockObject(level.Name, slack.NewTextBlockObject(slack.PlainTextType, level.ButtonLabel(), true, false), nil))
+	}
+	return options
+}
+
+// Slack treats <, > and & as markup, even inside code blocks
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Shows the HTML that's going to end up on the page, so that anything the
+// converter mangled is obvious before it goes out. Drafts get a button to
+// publish them.
+func CreatePreviewMsg(updateID string, rendered template.HTML, url string, draft Draft, isDraft bool) (blocks []slack.Block) {
+	intro := "Here's how this will look on the status page:"
+	if !isDraft || !draft.pending() {
+		intro = "Here's how this looks on the status page:"
+	}
+	blocks = append(blocks, slack.NewSectionBlock(
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s\n```%s```", intro, slackEscaper.Replace(truncateText(string(rendered), 2500))), false, false),
+		nil,
+		nil,
+	))
+
+	var buttons []slack.BlockElement
+	if isDraft && draft.pending() {
+		blocks = append(blocks, slack.NewContextBlock(
+			"",
+			slack.NewTextBlockObject(slack.MarkdownType, ":memo: This is a draft. It stays off the page until someone publishes it.", false, false),
+		))
+		buttons = append(buttons, slack.NewButtonBlockElement(
+			CSPPublishDraft,
+			updateID,
+			slack.NewTextBlockObject(slack.PlainTextType, "Publish", false, false),
+		).WithStyle(slack.StylePrimary))
+	} else if isDraft {
+		blocks = append(blocks, slack.NewContextBlock(
+			"",
+			slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf(":white_check_mark: Published by <@%s>.", draft.PublishedBy), false, false),
+		))
+	}
+	if url != "" {
+		link := slack.NewButtonBlockElement(
+			CSPPreviewLink,
+			updateID,
+			slack.NewTextBlockObject(slack.PlainTextType, "Open preview", false, false),
+		)
+		link.URL = url
+		buttons = append(buttons, link)
+	}
+	if len(buttons) > 0 {
+		blocks = append(blocks, slack.NewActionBlock("", buttons...))
+	}
+	return blocks
+}
+
+// Planned work, which goes on the page ahead of time and then takes care of
+// itself
+func CreateMaintenanceModal() slack.ModalViewRequest {
+	description := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "What's being done, and what people will notice", false, false),
+		CSPMaintenanceDescription,
+	)
+	description.Multiline = true
+	descriptionInput := slack.NewInputBlock(
+		CSPMaintenanceDescription,
+		slack.NewTextBlockObject(slack.PlainTextType, "Description", false, false),
+		nil,
+		description,
+	)
+	descriptionInput.Optional = true
+
+	blocks := []slack.Block{
+		slack.NewInputBlock(
+			CSPMaintenanceTitle,
+			slack.NewTextBlockObject(slack.PlainTextType, "Title", false, false),
+			nil,
+			slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, "e.g. Replacing the Supernode 1 router", false, false), CSPMaintenanceTitle),
+		),
+		descriptionInput,
+		slack.NewInputBlock(
+			CSPMaintenanceStart,
+			slack.NewTextBlockObject(slack.PlainTextType, "Starts", false, false),
+			nil,
+			slack.NewDateTimePickerBlockElement(CSPMaintenanceStart),
+		),
+		slack.NewInputBlock(
+			CSPMaintenanceEnd,
+			slack.NewTextBlockObject(slack.PlainTextType, "Ends", false, false),
+			nil,
+			slack.NewDateTimePickerBlockElement(CSPMaintenanceEnd),
+		),
+	}
+	if len(config().Components) > 0 {
+		components := slack.NewInputBlock(
+			CSPComponentsBlock,
+			slack.NewTextBlockObject(slack.PlainTextType, "Affected components", false, false),
+			nil,
+			componentSelectElement(CSPComponentsBlock, nil),
+		)
+		components.Optional = true
+		blocks = append(blocks, components)
+	}
+
+	return slack.ModalViewRequest{
+		Type:       slack.VTModal,
+		CallbackID: CSPMaintenanceModal,
+		Title:      slack.NewTextBlockObject(slack.PlainTextType, "Schedule maintenance", false, false),
+		Submit:     slack.NewTextBlockObject(slack.PlainTextType, "Schedule", false, false),
+		Close:      slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks:     slack.Blocks{BlockSet: blocks},
+	}
+}
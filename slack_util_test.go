@@ -1,7 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
 )
 
 func TestMrkdwnToHTMLAngleBrackets(t *testing.T) {
@@ -23,3 +32,364 @@ func TestMrkdwnToHTMLAngleBrackets(t *testing.T) {
 		}
 	}
 }
+
+func TestParseStatusChannels(t *testing.T) {
+	channels := parseStatusChannels("C0123:Backbone, C0456:Hubs,,C0789")
+	expected := []StatusChannel{
+		{ID: "C0123", Component: "Backbone"},
+		{ID: "C0456", Component: "Hubs"},
+		{ID: "C0789", Component: ""},
+	}
+	if len(channels) != len(expected) {
+		t.Fatalf("Expected %d channels, got %d: %+v", len(expected), len(channels), channels)
+	}
+	for i := range expected {
+		if channels[i] != expected[i] {
+			t.Errorf("Channel %d did not match.\nExpected: '%+v'\nReceived: '%+v'", i, expected[i], channels[i])
+		}
+	}
+}
+
+func TestCanPublishAllowList(t *testing.T) {
+	app := CSPSlack{workspace: SlackWorkspace{BotID: "UBOT"}}
+	if !app.canPublish("USOMEONE") {
+		t.Errorf("Everyone should be able to publish when nobody is listed")
+	}
+
+	app.workspace.Publishers = splitList("UADMIN, UOTHER,")
+	for user, expected := range map[string]bool{
+		"UADMIN":   true,
+		"UOTHER":   true,
+		"UBOT":     true,
+		"USOMEONE": false,
+	} {
+		if app.canPublish(user) != expected {
+			t.Errorf("Expected canPublish(%s) to be %v", user, expected)
+		}
+	}
+}
+
+func TestCanPublishGroups(t *testing.T) {
+	up := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !up {
+			w.Write([]byte(`{"ok": false, "error": "ratelimited"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true, "users": ["UVOLUNTEER"]}`))
+	}))
+	defer server.Close()
+	app := CSPSlack{
+		workspace:       SlackWorkspace{BotID: "UBOT", PublisherGroups: []string{"S0123"}},
+		slackAPI:        slack.New("token", slack.OptionAPIURL(server.URL+"/")),
+		publisherGroups: &publisherGroupCache{},
+	}
+
+	// Slack being down before we've ever heard back shouldn't lock everyone out
+	if !app.canPublish("USOMEONE") {
+		t.Error("Expected everyone to be let through until we know who's in the groups")
+	}
+
+	up = true
+	if !app.canPublish("UVOLUNTEER") || app.canPublish("USOMEONE") {
+		t.Error("Expected only group members to publish once we know who they are")
+	}
+
+	// Once we've heard back, a failed refresh keeps the last list
+	up = false
+	app.publisherGroups.fetched = time.Time{}
+	if !app.canPublish("UVOLUNTEER") || app.canPublish("USOMEONE") {
+		t.Error("Expected the last list to be kept when Slack is down")
+	}
+}
+
+func TestBuildStatusReplies(t *testing.T) {
+	defer func(s *CSPStore) { store = s }(store)
+	store = &CSPStore{}
+
+	reply := func(user string, ts string, text string) slack.Message {
+		return slack.Message{Msg: slack.Msg{User: user, Timestamp: ts, ThreadTimestamp: "1709294400.000100", Text: text}}
+	}
+	composed := reply("UBOT", "1709294400.000300", "Monitoring the fix")
+	store.setComposedUpdate(slackUpdateID("C123", composed.Timestamp), ComposedUpdate{Author: "U1"})
+	// Slack doesn't promise any particular order, so mix them up
+	thread := []slack.Message{
+		reply("UADMIN", "1709294400.000500", "<@UBOT> Resolved, all good"),
+		reply("UADMIN", "1709294400.000100", "<@UBOT> Backbone is down"),
+		reply("USOMEONE", "1709294400.000400", "<@UBOT> Resolved?"),
+		composed,
+		reply("UADMIN", "1709294400.000200", "Looking into it"),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/conversations.replies":
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "messages": thread})
+		case "/users.info":
+			fmt.Fprintf(w, `{"ok": true, "user": {"id": %q, "real_name": "Name of %s"}}`, r.FormValue("user"), r.FormValue("user"))
+		case "/team.info":
+			w.Write([]byte(`{"ok": true, "team": {"domain": "example"}}`))
+		default:
+			t.Errorf("Unexpected call to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	api := slack.New("token", slack.OptionAPIURL(server.URL+"/"))
+	app := CSPSlack{
+		workspace:   SlackWorkspace{BotID: "UBOT", Publishers: []string{"UADMIN"}},
+		slackAPI:    api,
+		slackSocket: socketmode.New(api),
+	}
+
+	replies, err := app.buildStatusReplies("C123", "1709294400.000100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The parent, the chatter and the outsider's question should all be left out
+	if len(replies) != 2 {
+		t.Fatalf("Expected 2 replies, got %d: %+v", len(replies), replies)
+	}
+	if replies[0].SentBy != "Name of U1" || replies[0].State != "monitoring" {
+		t.Errorf("Expected the composed follow-up first, credited to whoever wrote it, got %+v", replies[0])
+	}
+	if replies[1].SentBy != "Name of UADMIN" || replies[1].State != "resolved" {
+		t.Errorf("Expected the resolution last, got %+v", replies[1])
+	}
+}
+
+func TestBuildStatusUpdateUnknownAuthor(t *testing.T) {
+	defer func(s *CSPStore) { store = s }(store)
+	store = &CSPStore{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/users.info":
+			w.Write([]byte(`{"ok": false, "error": "user_not_found"}`))
+		case "/team.info":
+			w.Write([]byte(`{"ok": true, "team": {"domain": "example"}}`))
+		}
+	}))
+	defer server.Close()
+	api := slack.New("token", slack.OptionAPIURL(server.URL+"/"))
+	app := CSPSlack{workspace: SlackWorkspace{BotID: "UBOT"}, slackAPI: api, slackSocket: socketmode.New(api)}
+
+	update, err := app.buildStatusUpdate(StatusChannel{ID: "C123"}, slack.Message{Msg: slack.Msg{Timestamp: "1709294400.000100", Text: "<@UBOT> Disk full on db1"}})
+	if err != nil {
+		t.Fatalf("Expected one bad author not to stop the page, got %s", err)
+	}
+	if update.SentBy != "Unknown" {
+		t.Errorf("Expected a placeholder name, got %q", update.SentBy)
+	}
+}
+
+func TestIntegrationName(t *testing.T) {
+	for expected, message := range map[string]slack.Message{
+		"PagerDuty":      {Msg: slack.Msg{Username: "pd", BotProfile: &slack.BotProfile{Name: "PagerDuty"}}},
+		"alertmanager":   {Msg: slack.Msg{Username: "alertmanager"}},
+		"an integration": {},
+	} {
+		if name := integrationName(message); name != expected {
+			t.Errorf("Expected %q, got %q", expected, name)
+		}
+	}
+}
+
+func TestCreatePublishModal(t *testing.T) {
+	modal := CreatePublishModal("C999/1.2", []StatusChannel{{ID: "C123"}, {ID: "C456"}}, map[string]string{"C123": "status", "C456": "status-hubs"})
+	if modal.CallbackID != CSPPublishModal || modal.PrivateMetadata != "C999/1.2" {
+		t.Errorf("Expected the modal to remember which message it's publishing, got %q / %q", modal.CallbackID, modal.PrivateMetadata)
+	}
+	if len(modal.Blocks.BlockSet) != 1 {
+		t.Fatalf("Expected just the channel picker, got %d blocks", len(modal.Blocks.BlockSet))
+	}
+	if input, ok := modal.Blocks.BlockSet[0].(*slack.InputBlock); !ok || input.BlockID != CSPComposeChannel {
+		t.Errorf("Expected the status channel input, got %+v", modal.Blocks.BlockSet[0])
+	}
+}
+
+func TestCreateFollowUpModal(t *testing.T) {
+	modal := CreateFollowUpModal("C123/1.2")
+	if modal.CallbackID != CSPFollowUpModal || modal.PrivateMetadata != "C123/1.2" {
+		t.Errorf("Expected the modal to remember which update it's for, got %q / %q", modal.CallbackID, modal.PrivateMetadata)
+	}
+}
+
+// Collects the action IDs of every button in a view
+func viewButtons(blocks []slack.Block) (actions []string) {
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *slack.ActionBlock:
+			for _, element := range b.Elements.ElementSet {
+				if button, ok := element.(*slack.ButtonBlockElement); ok {
+					actions = append(actions, button.ActionID)
+				}
+			}
+		case *slack.SectionBlock:
+			if b.Accessory != nil && b.Accessory.ButtonElement != nil {
+				actions = append(actions, b.Accessory.ButtonElement.ActionID)
+			}
+		}
+	}
+	return actions
+}
+
+func TestCreateHomeView(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.SeverityLevels = parseSeverityLevels(`
+			info|Info|ok|information_source
+			major_outage|Major outage|error|fire
+		`)
+	})
+	updates := []homeUpdate{{ID: "C123/1.2", Text: "Backbone is down", Emoji: "fire", Age: "5m"}}
+	maintenance := []Maintenance{{ID: "m1", Title: "Router swap", Start: time.Unix(1709294400, 0), End: time.Unix(1709298000, 0)}}
+
+	expected := []string{
+		CSPHomeSetSeverityPrefix + "major_outage",
+		CSPHomeSetSeverityPrefix + "info",
+		CSPHomeResolve,
+		CSPHomeFollowUp,
+		CSPHomeUnpin,
+		CSPMaintenanceCancel,
+	}
+	actions := viewButtons(CreateHomeView(updates, maintenance, true).Blocks.BlockSet)
+	if strings.Join(actions, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected buttons %v, got %v", expected, actions)
+	}
+
+	if actions := viewButtons(CreateHomeView(updates, maintenance, false).Blocks.BlockSet); len(actions) != 0 {
+		t.Errorf("Expected no buttons for people who can't manage the page, got %v", actions)
+	}
+
+	// A long calendar still has to fit in the tab
+	var updatesFull []homeUpdate
+	for i := 0; i < 20; i++ {
+		updatesFull = append(updatesFull, homeUpdate{ID: fmt.Sprintf("C123/%d.0", i), Text: "Backbone is down", State: "identified"})
+	}
+	var calendar []Maintenance
+	for i := 0; i < 40; i++ {
+		calendar = append(calendar, Maintenance{ID: fmt.Sprintf("m%d", i), Title: "Router swap", Start: time.Unix(1709294400, 0), End: time.Unix(1709298000, 0)})
+	}
+	full := CreateHomeView(updatesFull, calendar, true).Blocks.BlockSet
+	if len(full) > 100 {
+		t.Errorf("Slack only takes 100 blocks in a Home tab, got %d", len(full))
+	}
+	if context, ok := full[len(full)-1].(*slack.ContextBlock); !ok || !strings.Contains(context.ContextElements.Elements[0].(*slack.TextBlockObject).Text, "+30 more") {
+		t.Error("Expected the maintenance that didn't fit to be counted")
+	}
+
+	empty := false
+	for _, block := range CreateHomeView(nil, nil, true).Blocks.BlockSet {
+		if section, ok := block.(*slack.SectionBlock); ok && strings.Contains(section.Text.Text, "Nothing is pinned") {
+			empty = true
+		}
+	}
+	if !empty {
+		t.Error("Expected the Home tab to say when nothing is pinned")
+	}
+}
+
+func TestComposedUpdateMetadata(t *testing.T) {
+	defer func(s *CSPStore) { store = s }(store)
+	store = &CSPStore{}
+	app := CSPSlack{workspace: SlackWorkspace{BotID: "UBOT"}}
+
+	// Slack hands the metadata back as JSON, so numbers come back as floats
+	message := func(user string) (message slack.Message) {
+		raw, err := json.Marshal(slack.Message{Msg: slack.Msg{
+			User:      user,
+			Timestamp: "1.2",
+			Text:      "Backbone is down",
+			Metadata:  composedUpdateMetadata(ComposedUpdate{Author: "U1", Internal: true, ExpectedResolution: time.Unix(1709294400, 0)}),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.Unmarshal(raw, &message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return message
+	}
+
+	composed, ok := app.composedUpdate("C123", message("UBOT"))
+	if !ok || composed.Author != "U1" || !composed.Internal || composed.ExpectedResolution.Unix() != 1709294400 {
+		t.Errorf("Expected the update to be recognised from its metadata, got %+v", composed)
+	}
+	if !app.isStatusUpdate("C123", message("UBOT")) {
+		t.Error("Expected an update we posted to count without mentioning us")
+	}
+	if _, ok := app.composedUpdate("C123", message("USOMEONE")); ok {
+		t.Error("Expected only our own messages to be trusted")
+	}
+}
+
+func TestCreatePreviewMsgEscapesHTML(t *testing.T) {
+	blocks := CreatePreviewMsg("C123/1.2", MrkdwnToHTML("*down* for <everyone> & more"), "", Draft{Author: "U1"}, true)
+	section, ok := blocks[0].(*slack.SectionBlock)
+	if !ok {
+		t.Fatalf("Expected a section first, got %T", blocks[0])
+	}
+	if strings.ContainsAny(strings.Split(section.Text.Text, "```")[1], "<>") {
+		t.Errorf("Slack would read the preview as markup: %s", section.Text.Text)
+	}
+	if len(blocks) != 3 {
+		t.Errorf("Expected a draft notice and a publish button, got %d blocks", len(blocks))
+	}
+}
+
+func TestCreateReminderMsgBlockLimit(t *testing.T) {
+	var reminders []ReminderInfo
+	for i := 0; i < 40; i++ {
+		reminders = append(reminders, ReminderInfo{userID: "U1", link: "https://example.com", at: time.Unix(1709294400, 0), updateID: fmt.Sprintf("C123/%d.0", i)})
+	}
+	blocks := CreateReminderMsg("Pinned:", reminders)
+	if len(blocks) > 50 {
+		t.Errorf("Slack only takes 50 blocks, got %d", len(blocks))
+	}
+	found := false
+	for _, block := range blocks {
+		if section, ok := block.(*slack.SectionBlock); ok && strings.Contains(section.Text.Text, "+25 more") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the reminders that didn't fit to be counted")
+	}
+}
+
+func TestReplaceReminderActions(t *testing.T) {
+	blocks := CreateReminderMsg("Pinned:", []ReminderInfo{
+		{userID: "U1", link: "https://example.com/1", at: time.Unix(1709294400, 0), updateID: "C123/1.2"},
+		{userID: "U2", link: "https://example.com/2", at: time.Unix(1708689600, 0), status: "warning", updateID: "C123/3.4"},
+	})
+	blocks = replaceReminderActions(blocks, "C123/1.2", "Unpinned")
+
+	var actions, notes int
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case *slack.ActionBlock:
+			actions++
+			if b.BlockID != "C123/3.4" {
+				t.Errorf("Expected the buttons for C123/1.2 to be gone, found %s", b.BlockID)
+			}
+		case *slack.ContextBlock:
+			if b.BlockID == "C123/1.2" {
+				notes++
+			}
+		}
+	}
+	if actions != 1 || notes != 1 {
+		t.Errorf("Expected one set of buttons and one note, got %d and %d", actions, notes)
+	}
+}
+
+func TestSlackTSToTime(t *testing.T) {
+	first, second := slackTSToTime("1709294400.000100"), slackTSToTime("1709294400.000200")
+	if !first.Before(second) {
+		t.Errorf("Expected %s to be before %s", first, second)
+	}
+	if expected := time.Unix(1709294400, 100000); !first.Equal(expected) {
+		t.Errorf("Expected %s, got %s", expected, first)
+	}
+}
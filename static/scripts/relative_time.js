@@ -0,0 +1,44 @@
+// Shows times as "2 hours ago" in the reader's own language. The exact time,
+// in their own time zone, stays around for hovering and screen readers.
+document.addEventListener("DOMContentLoaded", function() {
+    if (!window.Intl || !Intl.RelativeTimeFormat) {
+        return;
+    }
+
+    var units = [
+        ["year", 365 * 24 * 60 * 60],
+        ["month", 30 * 24 * 60 * 60],
+        ["week", 7 * 24 * 60 * 60],
+        ["day", 24 * 60 * 60],
+        ["hour", 60 * 60],
+        ["minute", 60],
+        ["second", 1],
+    ];
+    var relative = new Intl.RelativeTimeFormat(undefined, { numeric: "auto" });
+    var absolute = new Intl.DateTimeFormat(undefined, { dateStyle: "medium", timeStyle: "short" });
+
+    document.querySelectorAll("time.relative-time").forEach(function(element) {
+        var when = new Date(element.getAttribute("datetime"));
+        if (isNaN(when)) {
+            return;
+        }
+
+        var seconds = (when - Date.now()) / 1000;
+        var unit = units[units.length - 1];
+        for (var i = 0; i < units.length; i++) {
+            if (Math.abs(seconds) >= units[i][1]) {
+                unit = units[i];
+                break;
+            }
+        }
+
+        var exact = absolute.format(when);
+        element.title = exact;
+        element.textContent = relative.format(Math.round(seconds / unit[1]), unit[0]);
+
+        var hidden = document.createElement("span");
+        hidden.className = "visually-hidden";
+        hidden.textContent = " (" + exact + ")";
+        element.appendChild(hidden);
+    });
+});
@@ -0,0 +1,482 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Most of what we know lives in the chat backend itself (reactions, pins),
+// but some things have nowhere to go there. Those live in a small JSON file.
+type CSPStore struct {
+	mu   sync.Mutex
+	path string
+	data storeData
+}
+
+type storeData struct {
+	// Components picked for an update, keyed by update ID
+	Components map[string][]string `json:"components,omitempty"`
+
+	// Every time a component changed state, keyed by component name
+	ComponentHistory map[string][]SeverityChange `json:"component_history,omitempty"`
+
+	// Where each incident is at, oldest first, keyed by update ID
+	IncidentStates map[string][]StateChange `json:"incident_states,omitempty"`
+
+	// Updates written in the compose modal, keyed by update ID
+	ComposedUpdates map[string]ComposedUpdate `json:"composed_updates,omitempty"`
+
+	// Updates that needed a second person to approve them, keyed by update ID
+	Approvals map[string]Approval `json:"approvals,omitempty"`
+
+	// Updates that were posted as drafts, keyed by update ID
+	Drafts map[string]Draft `json:"drafts,omitempty"`
+
+	// Tokens for the preview links, keyed by update ID
+	PreviewTokens map[string]string `json:"preview_tokens,omitempty"`
+
+	// Scheduled maintenance, keyed by its ID
+	Maintenance map[string]Maintenance `json:"maintenance,omitempty"`
+
+	// When updates last came off the page's current status, keyed by update ID
+	Unpinned map[string]time.Time `json:"unpinned,omitempty"`
+
+	// When someone picked for pinned updates to come down, keyed by update ID
+	PinExpiries map[string]PinExpiry `json:"pin_expiries,omitempty"`
+
+	// When pinned updates were first seen pinned, keyed by update ID
+	Pinned map[string]time.Time `json:"pinned,omitempty"`
+
+	// When pinned updates were first seen marked OK, keyed by update ID
+	MarkedOK map[string]time.Time `json:"marked_ok,omitempty"`
+
+	// When pinned updates can show up in reminders again, keyed by update ID
+	Snoozes map[string]time.Time `json:"snoozes,omitempty"`
+
+	// When approvals were turned on. Nobody was ever going to be asked about
+	// updates from before then.
+	ApprovalsSince time.Time `json:"approvals_since"`
+}
+
+// The bot posts updates written in the compose modal itself, so whatever the
+// chat backend knows about who wrote them is wrong. Keep the truth here.
+type ComposedUpdate struct {
+	Author             string    `json:"author"`
+	ExpectedResolution time.Time `json:"expected_resolution"`
+	Internal           bool      `json:"internal,omitempty"`
+}
+
+type SeverityChange struct {
+	Time     time.Time `json:"time"`
+	Severity string    `json:"severity"`
+}
+
+// Until main loads the real thing, keep everything in memory.
+var store = &CSPStore{}
+
+// Loads the store from disk, or starts a fresh one if the file isn't there yet.
+// An empty path keeps everything in memory.
+func LoadStore(path string) (*CSPStore, error) {
+	s := &CSPStore{path: path}
+	if path == "" {
+		return s, nil
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Printf("No state file at %s yet, starting fresh.\n", path)
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(raw, &s.data)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write the store out. Callers must hold the lock.
+func (s *CSPStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write somewhere else first so a crash can't leave us with half a file
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".csp-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(raw)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *CSPStore) updateComponents(updateID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Components[updateID]
+}
+
+func (s *CSPStore) setUpdateComponents(updateID string, components []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Components == nil {
+		s.data.Components = make(map[string][]string)
+	}
+	if len(components) == 0 {
+		delete(s.data.Components, updateID)
+	} else {
+		s.data.Components[updateID] = components
+	}
+	return s.save()
+}
+
+func (s *CSPStore) componentHistory(component string) []SeverityChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SeverityChange(nil), s.data.ComponentHistory[component]...)
+}
+
+// Notes down a component's severity if it's different from last time. Anything
+// older than we'll ever show gets thrown out, except for the change that was
+// still in effect back then.
+func (s *CSPStore) recordComponentSeverity(component string, severity string, at time.Time, keep time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.ComponentHistory == nil {
+		s.data.ComponentHistory = make(map[string][]SeverityChange)
+	}
+	history := s.data.ComponentHistory[component]
+	if len(history) > 0 && history[len(history)-1].Severity == severity {
+		return nil
+	}
+	history = append(history, SeverityChange{Time: at, Severity: severity})
+
+	cutoff := at.Add(-keep)
+	for len(history) > 1 && !history[1].Time.After(cutoff) {
+		history = history[1:]
+	}
+	s.data.ComponentHistory[component] = history
+	return s.save()
+}
+
+func (s *CSPStore) incidentStates(updateID string) []StateChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]StateChange(nil), s.data.IncidentStates[updateID]...)
+}
+
+// Moves an incident on to a new state. Setting the state it's already in
+// does nothing.
+func (s *CSPStore) addIncidentState(updateID string, state string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.IncidentStates == nil {
+		s.data.IncidentStates = make(map[string][]StateChange)
+	}
+	history := s.data.IncidentStates[updateID]
+	if len(history) > 0 && history[len(history)-1].State == state {
+		return nil
+	}
+	s.data.IncidentStates[updateID] = append(history, StateChange{Time: at, State: state})
+	return s.save()
+}
+
+func (s *CSPStore) composedUpdate(updateID string) (composed ComposedUpdate, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	composed, ok = s.data.ComposedUpdates[updateID]
+	return composed, ok
+}
+
+func (s *CSPStore) setComposedUpdate(updateID string, composed ComposedUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.ComposedUpdates == nil {
+		s.data.ComposedUpdates = make(map[string]ComposedUpdate)
+	}
+	s.data.ComposedUpdates[updateID] = composed
+	return s.save()
+}
+
+func (s *CSPStore) approval(updateID string) (approval Approval, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	approval, ok = s.data.Approvals[updateID]
+	return approval, ok
+}
+
+func (s *CSPStore) setApproval(updateID string, approval Approval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Approvals == nil {
+		s.data.Approvals = make(map[string]Approval)
+	}
+	s.data.Approvals[updateID] = approval
+	return s.save()
+}
+
+// Starts the clock when approvals get turned on, and stops it again when
+// they're turned off
+func (s *CSPStore) approvalsSince(on bool, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case on && s.data.ApprovalsSince.IsZero():
+		s.data.ApprovalsSince = now
+	case !on && !s.data.ApprovalsSince.IsZero():
+		s.data.ApprovalsSince = time.Time{}
+	default:
+		return s.data.ApprovalsSince
+	}
+	err := s.save()
+	if err != nil {
+		log.Println(err)
+	}
+	return s.data.ApprovalsSince
+}
+
+func (s *CSPStore) draft(updateID string) (draft Draft, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	draft, ok = s.data.Drafts[updateID]
+	return draft, ok
+}
+
+func (s *CSPStore) setDraft(updateID string, draft Draft) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Drafts == nil {
+		s.data.Drafts = make(map[string]Draft)
+	}
+	s.data.Drafts[updateID] = draft
+	return s.save()
+}
+
+// Whether an update is still a draft nobody has published yet
+func (s *CSPStore) isDraft(updateID string) bool {
+	draft, ok := s.draft(updateID)
+	return ok && draft.pending()
+}
+
+func (s *CSPStore) previewToken(updateID string) (token string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok = s.data.PreviewTokens[updateID]
+	return token, ok
+}
+
+// Hands out the preview token for an update, making one up if it doesn't
+// have one yet
+func (s *CSPStore) createPreviewToken(updateID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token, ok := s.data.PreviewTokens[updateID]; ok {
+		return token, nil
+	}
+	// Preview links are public, so they need to be hard to guess
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if s.data.PreviewTokens == nil {
+		s.data.PreviewTokens = make(map[string]string)
+	}
+	s.data.PreviewTokens[updateID] = token
+	return token, s.save()
+}
+
+func (s *CSPStore) maintenances() (maintenances []Maintenance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, maintenance := range s.data.Maintenance {
+		maintenances = append(maintenances, maintenance)
+	}
+	return maintenances
+}
+
+func (s *CSPStore) maintenance(id string) (maintenance Maintenance, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maintenance, ok = s.data.Maintenance[id]
+	return maintenance, ok
+}
+
+func (s *CSPStore) setMaintenance(maintenance Maintenance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Maintenance == nil {
+		s.data.Maintenance = make(map[string]Maintenance)
+	}
+	s.data.Maintenance[maintenance.ID] = maintenance
+	return s.save()
+}
+
+// Forgets maintenance that ended before the cutoff
+func (s *CSPStore) pruneMaintenance(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pruned := false
+	for id, maintenance := range s.data.Maintenance {
+		if maintenance.End.Before(before) {
+			delete(s.data.Maintenance, id)
+			pruned = true
+		}
+	}
+	if !pruned {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *CSPStore) unpinnedAt(updateID string) (at time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, ok = s.data.Unpinned[updateID]
+	return at, ok
+}
+
+func (s *CSPStore) recordUnpinned(updateID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Unpinned == nil {
+		s.data.Unpinned = make(map[string]time.Time)
+	}
+	s.data.Unpinned[updateID] = at
+	return s.save()
+}
+
+// Forgets when things came unpinned before the cutoff
+func (s *CSPStore) pruneUnpinned(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pruned := false
+	for id, at := range s.data.Unpinned {
+		if at.Before(before) {
+			delete(s.data.Unpinned, id)
+			pruned = true
+		}
+	}
+	if !pruned {
+		return nil
+	}
+	return s.save()
+}
+
+func (s *CSPStore) pinExpiry(updateID string) (expiry PinExpiry, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok = s.data.PinExpiries[updateID]
+	return expiry, ok
+}
+
+func (s *CSPStore) setPinExpiry(updateID string, expiry PinExpiry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.PinExpiries == nil {
+		s.data.PinExpiries = make(map[string]PinExpiry)
+	}
+	s.data.PinExpiries[updateID] = expiry
+	return s.save()
+}
+
+func (s *CSPStore) clearPinExpiry(updateID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.PinExpiries[updateID]; !ok {
+		return nil
+	}
+	delete(s.data.PinExpiries, updateID)
+	return s.save()
+}
+
+func (s *CSPStore) pinnedSince(updateID string) (since time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	since, ok = s.data.Pinned[updateID]
+	return since, ok
+}
+
+// Starts the clock when an update is first seen pinned, and stops it again
+// once it comes down
+func (s *CSPStore) recordPinned(updateID string, pinned bool, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, recorded := s.data.Pinned[updateID]
+	switch {
+	case pinned && !recorded:
+		if s.data.Pinned == nil {
+			s.data.Pinned = make(map[string]time.Time)
+		}
+		s.data.Pinned[updateID] = at
+	case !pinned && recorded:
+		delete(s.data.Pinned, updateID)
+	default:
+		return nil
+	}
+	return s.save()
+}
+
+func (s *CSPStore) markedOK(updateID string) (since time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	since, ok = s.data.MarkedOK[updateID]
+	return since, ok
+}
+
+// Starts the clock when an update is marked OK, and stops it again if it
+// gets marked as anything else
+func (s *CSPStore) recordMarkedOK(updateID string, ok bool, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, recorded := s.data.MarkedOK[updateID]
+	switch {
+	case ok && !recorded:
+		if s.data.MarkedOK == nil {
+			s.data.MarkedOK = make(map[string]time.Time)
+		}
+		s.data.MarkedOK[updateID] = at
+	case !ok && recorded:
+		delete(s.data.MarkedOK, updateID)
+	default:
+		return nil
+	}
+	return s.save()
+}
+
+// Whether someone asked for an update to be left out of reminders for now
+func (s *CSPStore) snoozed(updateID string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	until, ok := s.data.Snoozes[updateID]
+	return ok && now.Before(until)
+}
+
+func (s *CSPStore) snoozeReminders(updateID string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data.Snoozes == nil {
+		s.data.Snoozes = make(map[string]time.Time)
+	}
+	s.data.Snoozes[updateID] = until
+	return s.save()
+}
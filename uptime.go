@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// How far back the uptime bars go
+const uptimeDays = 90
+
+// How often we check whether any component has changed state
+const componentHistoryInterval = time.Minute
+
+// How one component did over the course of a day. Degraded doesn't count
+// against uptime, only outages do.
+type UptimeDay struct {
+	Date     time.Time
+	Severity string
+	Tracked  time.Duration
+	Degraded time.Duration
+	Outage   time.Duration
+}
+
+type ComponentUptime struct {
+	Days    []UptimeDay
+	Percent float64
+	HasData bool
+}
+
+// Keeps an eye on the page and writes down whenever a component changes
+// state, so there's something to work out uptime from later.
+func watchComponentHistory(csp CSPService) {
+	for {
+		pinnedUpdates, _ := csp.Page().current()
+		now := time.Now()
+		for _, component := range config().Components {
+			severity := componentSeverity(component.Name, pinnedUpdates)
+			if severity == "" {
+				severity = resolvedSeverity()
+			}
+			err := store.recordComponentSeverity(component.Name, severity, now, uptimeDays*24*time.Hour)
+			if err != nil {
+				log.Printf("Could not record history for %s: %s\n", component.Name, err)
+			}
+		}
+		time.Sleep(componentHistoryInterval)
+	}
+}
+
+// Works out how available a component was each day for the last few days,
+// given every time it changed state. Time before we started keeping track
+// doesn't count either way.
+func componentUptime(history []SeverityChange, now time.Time, days int) (uptime ComponentUptime) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var tracked, outage time.Duration
+	for i := days - 1; i >= 0; i-- {
+		day := UptimeDay{Date: today.AddDate(0, 0, -i)}
+		start := day.Date
+		end := start.AddDate(0, 0, 1)
+		if end.After(now) {
+			end = now
+		}
+
+		for j, change := range history {
+			from, to := change.Time, now
+			if j+1 < len(history) {
+				to = history[j+1].Time
+			}
+			if from.Before(start) {
+				from = start
+			}
+			if to.After(end) {
+				to = end
+			}
+			if !to.After(from) {
+				continue
+			}
+
+			span := to.Sub(from)
+			day.Tracked += span
+			switch severityImpact(change.Severity) {
+			case SeverityWarn:
+				day.Degraded += span
+			case SeverityError:
+				day.Outage += span
+			}
+			if severityRank(change.Severity) > severityRank(day.Severity) {
+				day.Severity = change.Severity
+			}
+		}
+
+		tracked += day.Tracked
+		outage += day.Outage
+		uptime.Days = append(uptime.Days, day)
+	}
+
+	if tracked > 0 {
+		uptime.HasData = true
+		uptime.Percent = 100 * float64(tracked-outage) / float64(tracked)
+	}
+	return uptime
+}
+
+func (uptime ComponentUptime) PercentString() string {
+	return fmt.Sprintf("%.2f%%", uptime.Percent)
+}
+
+// The color of the day's bar
+func (day UptimeDay) Class() string {
+	switch severityImpact(day.Severity) {
+	case SeverityOK:
+		return "bg-success"
+	case SeverityWarn:
+		return "bg-warning"
+	case SeverityError:
+		return "bg-danger"
+	}
+	return "bg-secondary-subtle"
+}
+
+// What you see when you hover over the day's bar
+func (day UptimeDay) Tooltip() string {
+	date := day.Date.Format("Jan 2, 2006")
+	switch {
+	case day.Tracked == 0:
+		return date + ": No data"
+	case day.Outage > 0 && day.Degraded > 0:
+		return fmt.Sprintf("%s: %s outage, %s degraded", date, humanDuration(day.Outage), humanDuration(day.Degraded))
+	case day.Outage > 0:
+		return fmt.Sprintf("%s: %s outage", date, humanDuration(day.Outage))
+	case day.Degraded > 0:
+		return fmt.Sprintf("%s: %s degraded", date, humanDuration(day.Degraded))
+	}
+	return date + ": No incidents"
+}
+
+// Rounds a duration off to something like "2h 5m"
+func humanDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "<1m"
+	}
+	hours := d / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	if hours == 0 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	if minutes == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh %dm", hours, minutes)
+}
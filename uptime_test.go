@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComponentUptime(t *testing.T) {
+	location := time.FixedZone("EST", -5*60*60)
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, location)
+	history := []SeverityChange{
+		// Started watching halfway through the 8th
+		{Time: time.Date(2024, 3, 8, 12, 0, 0, 0, location), Severity: SeverityOK},
+		// Degraded over midnight into the 9th, then six hours of outage
+		{Time: time.Date(2024, 3, 8, 23, 0, 0, 0, location), Severity: SeverityWarn},
+		{Time: time.Date(2024, 3, 9, 1, 0, 0, 0, location), Severity: SeverityError},
+		{Time: time.Date(2024, 3, 9, 7, 0, 0, 0, location), Severity: SeverityOK},
+	}
+
+	uptime := componentUptime(history, now, 4)
+	if len(uptime.Days) != 4 {
+		t.Fatalf("Expected 4 days, got %d", len(uptime.Days))
+	}
+
+	expected := []struct {
+		severity string
+		tracked  time.Duration
+		degraded time.Duration
+		outage   time.Duration
+	}{
+		{"", 0, 0, 0},
+		{SeverityWarn, 12 * time.Hour, time.Hour, 0},
+		{SeverityError, 24 * time.Hour, time.Hour, 6 * time.Hour},
+		{SeverityOK, 12 * time.Hour, 0, 0},
+	}
+	for i, e := range expected {
+		day := uptime.Days[i]
+		if day.Severity != e.severity || day.Tracked != e.tracked || day.Degraded != e.degraded || day.Outage != e.outage {
+			t.Errorf("Day %d did not match.\nExpected: '%+v'\nReceived: '%+v'", i, e, day)
+		}
+	}
+
+	// 6 hours down out of 48 watched, degraded doesn't count
+	if !uptime.HasData || math.Abs(uptime.Percent-87.5) > 0.001 {
+		t.Errorf("Expected 87.5%% uptime, got %f", uptime.Percent)
+	}
+	if uptime.Days[0].Tooltip() != "Mar 7, 2024: No data" || uptime.Days[2].Tooltip() != "Mar 9, 2024: 6h outage, 1h degraded" {
+		t.Errorf("Unexpected tooltips: '%s', '%s'", uptime.Days[0].Tooltip(), uptime.Days[2].Tooltip())
+	}
+}
+
+func TestRecordComponentSeverityOnlyKeepsChanges(t *testing.T) {
+	s := &CSPStore{}
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	keep := 48 * time.Hour
+	severities := []string{SeverityOK, SeverityOK, SeverityWarn, SeverityWarn, SeverityOK}
+	for i, severity := range severities {
+		err := s.recordComponentSeverity("Backbone", severity, start.Add(time.Duration(i)*24*time.Hour), keep)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first OK is too old to matter, but the warning from day 2 was
+	// still going at the start of the window.
+	history := s.componentHistory("Backbone")
+	if len(history) != 2 || history[0].Severity != SeverityWarn || history[1].Severity != SeverityOK {
+		t.Errorf("Unexpected history: %+v", history)
+	}
+}
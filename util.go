@@ -1,5 +1,18 @@
 package main
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gomarkdown/markdown"
+	"github.com/microcosm-cc/bluemonday"
+)
+
 func stringInSlice(searchSlice []string, searchString string) bool {
 	for _, s := range searchSlice {
 		if s == searchString {
@@ -9,11 +22,102 @@ func stringInSlice(searchSlice []string, searchString string) bool {
 	return false
 }
 
-// Compares a string you give it to a string passed in the config
-func isRelevantReaction(reaction string) bool {
-	switch reaction {
-	case config.StatusOKEmoji, config.StatusWarnEmoji, config.StatusErrorEmoji:
-		return true
+// Random hex, for IDs and links that shouldn't be guessable
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
 	}
-	return false
+	return hex.EncodeToString(b), nil
+}
+
+// Splits up a comma separated list, skipping anything blank
+func splitList(value string) (items []string) {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Renders plain Markdown into sanitized HTML we can drop into the page.
+func MarkdownToHTML(md string) template.HTML {
+	maybeUnsafeHTML := markdown.ToHTML([]byte(md), nil, nil)
+	blueMondayHtml := bluemonday.UGCPolicy().SanitizeBytes(maybeUnsafeHTML)
+	return template.HTML(blueMondayHtml)
+}
+
+// The time zone we show times in, and where our days start and end
+func displayLocation() *time.Location {
+	c := config()
+	if c.Location == nil {
+		return time.Local
+	}
+	return c.Location
+}
+
+// Looks up CSP_TIMEZONE, falling back to the server's time zone
+func loadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Unknown time zone '%s', using the server's. %s\n", name, err)
+		return time.Local
+	}
+	return location
+}
+
+// Formats a point in time the way we show it on the page
+func timeToHumanTime(t time.Time) (hrt string) {
+	return t.In(displayLocation()).Format("2006-01-02 15:04:05 MST")
+}
+
+// For machines, like the page's script, to read
+func isoTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// Durations configured per severity, like "error=4h,warn=12h,24h". Where a
+// default makes sense, one without a severity goes for everything that isn't
+// listed.
+func parseSeverityDurations(key string, value string, allowDefault bool) map[string]time.Duration {
+	durations, problems := readSeverityDurations(key, value, allowDefault)
+	for _, problem := range problems {
+		log.Printf("%s. Skipping it.\n", problem)
+	}
+	return durations
+}
+
+// Does the work for parseSeverityDurations, and says what was wrong with any
+// entries it had to skip
+func readSeverityDurations(key string, value string, allowDefault bool) (durations map[string]time.Duration, problems []string) {
+	durations = make(map[string]time.Duration)
+	for _, entry := range splitList(value) {
+		severity, duration, found := strings.Cut(entry, "=")
+		if !found {
+			if !allowDefault {
+				problems = append(problems, fmt.Sprintf("%s entry '%s' needs a severity, like ok=24h", key, entry))
+				continue
+			}
+			severity, duration = "", entry
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(duration))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s has a bad duration '%s'. Use something like ok=24h,warn=72h", key, entry))
+			continue
+		}
+		durations[strings.TrimSpace(severity)] = d
+	}
+	return durations, problems
+}
+
+// The duration for a severity, or the default if it isn't listed. Zero
+// means there isn't one.
+func severityDuration(durations map[string]time.Duration, severity string) time.Duration {
+	if d, ok := durations[severity]; ok {
+		return d
+	}
+	return durations[""]
 }
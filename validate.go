@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Hex colors and CSS color names. The page's templates won't put anything
+// fancier, like rgb(), into a style attribute.
+var colorRegex = regexp.MustCompile(`^(#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})|[a-zA-Z]+)$`)
+
+// Checks everything we can before connecting to anything, so a typo gets a
+// clear message at startup instead of a crash or a page that's quietly wrong
+// later on. Returns every problem it finds, not just the first.
+func validateConfig(c Config, settings configSettings, sources []string, reminders bool) (problems []string) {
+	for _, source := range sources {
+		kind, arg, _ := strings.Cut(source, ":")
+		switch kind {
+		case "slack":
+			prefix := "CSP_SLACK_"
+			if arg != "" {
+				prefix += strings.ToUpper(arg) + "_"
+			}
+			problems = append(problems, requireSettings(settings, source, prefix+"ACCESS_TOKEN", prefix+"APP_TOKEN", prefix+"STATUS_CHANNEL")...)
+			problems = append(problems, checkTruncation(settings, prefix+"TRUNCATION")...)
+		case "discord":
+			problems = append(problems, requireSettings(settings, source, "CSP_DISCORD_TOKEN", "CSP_DISCORD_STATUS_CHANNEL")...)
+			problems = append(problems, checkTruncation(settings, "CSP_DISCORD_TRUNCATION")...)
+		case "mattermost":
+			problems = append(problems, requireSettings(settings, source, "CSP_MATTERMOST_URL", "CSP_MATTERMOST_TOKEN", "CSP_MATTERMOST_STATUS_CHANNEL")...)
+			problems = append(problems, checkTruncation(settings, "CSP_MATTERMOST_TRUNCATION")...)
+		case "file":
+			if arg == "" {
+				problems = append(problems, "The file source needs a path, like file:updates.json")
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("Unknown source '%s' in CSP_SOURCES. Use slack, slack:<name>, discord, mattermost or file:<path>", source))
+		}
+	}
+
+	if reminders {
+		if c.ReminderSchedule == "" {
+			problems = append(problems, "CSP_REMINDER_SCHEDULE is required to send reminders")
+		} else if _, err := cron.ParseStandard(c.ReminderSchedule); err != nil {
+			problems = append(problems, fmt.Sprintf("CSP_REMINDER_SCHEDULE '%s' isn't a valid cron expression: %s", c.ReminderSchedule, err))
+		}
+	}
+
+	// Otherwise held updates would show up on the page after a restart
+	if c.StateFile == "" {
+		if c.ApprovalMode != "" && c.ApprovalMode != ApprovalOff {
+			problems = append(problems, "CSP_APPROVAL_MODE needs CSP_STATE_FILE, to remember what's waiting on approval")
+		}
+		if c.Drafts {
+			problems = append(problems, "CSP_DRAFTS needs CSP_STATE_FILE, to remember what's still a draft")
+		}
+	}
+
+	// The lists get parsed leniently, skipping whatever's wrong, so catch that
+	// here instead
+	_, levelProblems := readSeverityLevels(settings.get("CSP_SEVERITY_LEVELS"))
+	problems = append(problems, levelProblems...)
+	_, componentProblems := readComponents(settings.get("CSP_COMPONENTS"))
+	problems = append(problems, componentProblems...)
+	_, maintenanceProblems := readRecurringMaintenance(settings.get("CSP_RECURRING_MAINTENANCE"))
+	problems = append(problems, maintenanceProblems...)
+	for _, window := range c.RecurringMaintenance {
+		for _, name := range window.Components {
+			if !hasComponent(c.Components, name) {
+				problems = append(problems, fmt.Sprintf("Recurring maintenance '%s' affects '%s', which isn't in CSP_COMPONENTS", window.Title, name))
+			}
+		}
+	}
+
+	for _, level := range c.SeverityLevels {
+		if level.Color != "" && !colorRegex.MatchString(level.Color) {
+			problems = append(problems, fmt.Sprintf("Severity level '%s' has a bad color '%s'. Use something like #fff3cd or orange", level.Name, level.Color))
+		}
+	}
+
+	for _, key := range []string{"CSP_UNPIN_AFTER_OK", "CSP_MAINTENANCE_REMINDER"} {
+		if value := settings.get(key); value != "" {
+			if _, err := time.ParseDuration(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s '%s' isn't a duration, like 24h or 90m", key, value))
+			}
+		}
+	}
+	// Pin expiries don't have a default, so every one needs a severity
+	problems = append(problems, checkSeverityDurations(settings, "CSP_PIN_EXPIRY", false)...)
+	problems = append(problems, checkSeverityDurations(settings, "CSP_REMINDER_AFTER", true)...)
+	problems = append(problems, checkSeverityDurations(settings, "CSP_ESCALATE_AFTER", true)...)
+
+	if timezone := settings.get("CSP_TIMEZONE"); timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			problems = append(problems, fmt.Sprintf("CSP_TIMEZONE '%s' isn't a time zone we know, like America/New_York", timezone))
+		}
+	}
+
+	for _, link := range c.NavLinks {
+		problems = append(problems, checkLink("nav_links", link)...)
+	}
+	for _, section := range c.FooterSections {
+		for _, link := range section.Links {
+			problems = append(problems, checkLink(fmt.Sprintf("footer section '%s'", section.Title), link)...)
+		}
+	}
+	return problems
+}
+
+// Complains about any of the settings that are empty
+func requireSettings(settings configSettings, source string, keys ...string) (problems []string) {
+	for _, key := range keys {
+		if strings.TrimSpace(settings.get(key)) == "" {
+			problems = append(problems, fmt.Sprintf("%s is required for the %s source", key, source))
+		}
+	}
+	return problems
+}
+
+// How many messages to read back. Blank leaves it up to the backend.
+func checkTruncation(settings configSettings, key string) []string {
+	value := settings.get(key)
+	if value == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+		return []string{fmt.Sprintf("%s should be a whole number of messages, not '%s'", key, value)}
+	}
+	return nil
+}
+
+// Durations per severity, which skip whatever they can't read
+func checkSeverityDurations(settings configSettings, key string, allowDefault bool) []string {
+	_, problems := readSeverityDurations(key, settings.get(key), allowDefault)
+	return problems
+}
+
+func hasComponent(components []Component, name string) bool {
+	for _, component := range components {
+		if normalizeComponentName(component.Name) == normalizeComponentName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkLink(where string, link Link) []string {
+	if link.Label == "" || link.URL == "" {
+		return []string{fmt.Sprintf("A link in %s needs both a label and a url", where)}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// Clears settings out of the environment so the ones in the test's file
+// settings show through. They come back once the test is done.
+func unsetenv(t *testing.T, keys ...string) {
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	settings := configSettings{
+		"CSP_SLACK_ACCESS_TOKEN":    "xoxb-token",
+		"CSP_SLACK_STATUS_CHANNEL":  "C0123",
+		"CSP_SLACK_TRUNCATION":      "lots",
+		"CSP_DISCORD_TRUNCATION":    "20",
+		"CSP_PIN_EXPIRY":            "ok=1d,168h",
+		"CSP_REMINDER_AFTER":        "error=4h,24h",
+		"CSP_SEVERITY_LEVELS":       "ok|OK|ok\nmeltdown|Meltdown|catastrophic",
+		"CSP_COMPONENTS":            "Core/Backbone;Backbone",
+		"CSP_RECURRING_MAINTENANCE": "Patching|DTSTART:20240312T020000Z RRULE:FREQ=WEEKLY|a while",
+	}
+	unsetenv(t, "CSP_SEVERITY_LEVELS", "CSP_COMPONENTS", "CSP_RECURRING_MAINTENANCE", "CSP_SLACK_ACCESS_TOKEN", "CSP_SLACK_APP_TOKEN", "CSP_SLACK_STATUS_CHANNEL", "CSP_SLACK_TRUNCATION", "CSP_DISCORD_STATUS_CHANNEL", "CSP_DISCORD_TRUNCATION", "CSP_PIN_EXPIRY", "CSP_REMINDER_AFTER", "CSP_ESCALATE_AFTER", "CSP_UNPIN_AFTER_OK", "CSP_MAINTENANCE_REMINDER", "CSP_TIMEZONE")
+	t.Setenv("CSP_DISCORD_TOKEN", "discord-token")
+
+	c := Config{
+		ReminderSchedule: "* 17 * *",
+		ApprovalMode:     ApprovalCritical,
+		SeverityLevels: []SeverityLevel{
+			{Name: "ok", Color: "#d1e7dd"},
+			{Name: "warn", Color: "rgba(255, 193, 7, 0.5)"},
+			{Name: "error", Color: "#ff00zz"},
+		},
+		NavLinks:             []Link{{Label: "Map"}},
+		Components:           parseComponents("Core/Backbone"),
+		RecurringMaintenance: []RecurringMaintenance{{Title: "Roof work", Components: []string{"Roof"}}},
+	}
+	problems := validateConfig(c, settings, []string{"slack", "discord", "carrier-pigeon"}, true)
+
+	for _, expected := range []string{
+		"CSP_SLACK_APP_TOKEN is required for the slack source",
+		"CSP_SLACK_TRUNCATION should be a whole number",
+		"CSP_DISCORD_STATUS_CHANNEL is required for the discord source",
+		"Unknown source 'carrier-pigeon'",
+		"CSP_REMINDER_SCHEDULE '* 17 * *' isn't a valid cron expression",
+		"Severity level 'error' has a bad color '#ff00zz'",
+		"CSP_PIN_EXPIRY has a bad duration 'ok=1d'",
+		"CSP_PIN_EXPIRY entry '168h' needs a severity",
+		"A link in nav_links needs both a label and a url",
+		"CSP_APPROVAL_MODE needs CSP_STATE_FILE",
+		"Severity level 'warn' has a bad color 'rgba(255, 193, 7, 0.5)'",
+		"Severity level 'meltdown' has unknown impact 'catastrophic'",
+		"Component 'Backbone' is listed twice",
+		"Recurring maintenance 'Patching' has a bad duration 'a while'",
+		"Recurring maintenance 'Roof work' affects 'Roof', which isn't in CSP_COMPONENTS",
+	} {
+		found := false
+		for _, problem := range problems {
+			found = found || strings.Contains(problem, expected)
+		}
+		if !found {
+			t.Errorf("Expected a problem like %q, got %q", expected, problems)
+		}
+	}
+	if len(problems) != 15 {
+		t.Errorf("Expected 15 problems, got %d: %q", len(problems), problems)
+	}
+}
+
+func TestValidConfigHasNoProblems(t *testing.T) {
+	settings := configSettings{
+		"CSP_MATTERMOST_URL":            "https://chat.example.com",
+		"CSP_MATTERMOST_TOKEN":          "token",
+		"CSP_MATTERMOST_STATUS_CHANNEL": "abc123",
+		"CSP_MATTERMOST_TRUNCATION":     "50",
+	}
+	unsetenv(t, "CSP_MATTERMOST_URL", "CSP_MATTERMOST_TOKEN", "CSP_MATTERMOST_STATUS_CHANNEL", "CSP_MATTERMOST_TRUNCATION", "CSP_PIN_EXPIRY", "CSP_REMINDER_AFTER", "CSP_ESCALATE_AFTER", "CSP_UNPIN_AFTER_OK", "CSP_MAINTENANCE_REMINDER", "CSP_TIMEZONE")
+
+	c := Config{ReminderSchedule: "0 17 * * *", SeverityLevels: defaultSeverityLevels(nil), ApprovalMode: ApprovalAll, Drafts: true, StateFile: "state.json"}
+	if problems := validateConfig(c, settings, []string{"mattermost", "file:updates.json"}, true); len(problems) > 0 {
+		t.Errorf("Expected no problems, got %q", problems)
+	}
+}